@@ -3,8 +3,9 @@ module go-examplar
 go 1.23
 
 require (
-	github.com/alexflint/go-arg v1.5.1 // indirect
-	github.com/alexflint/go-scalar v1.2.0 // indirect
-	github.com/magiconair/properties v1.8.9 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/alexflint/go-arg v1.5.1
+	github.com/magiconair/properties v1.8.9
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/alexflint/go-scalar v1.2.0 // indirect
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChainTransformer links transformers into one transformer, the output of a stage is the input of the next stage.
+// The stages are named so an error tells which stage failed, e.g.
+//
+//	Chain(Map(rename), Expand(expand)).Stage("sort", SortBy(priority)).When(notEmpty).Skip("sort")
+type ChainTransformer struct {
+	stages  []chainStage
+	skipped map[string]bool
+}
+
+type chainStage struct {
+	name        string
+	transformer Transformer
+	// Optional, the stage only runs when the condition holds for its input
+	when Predicate
+}
+
+var _ Transformer = &ChainTransformer{}
+
+// Chain links the transformers in order, the stages are named by their position and type, e.g. "2:ListExpandTransformer".
+func Chain(transformers ...Transformer) *ChainTransformer {
+	chain := &ChainTransformer{}
+	for _, transformer := range transformers {
+		name := fmt.Sprintf("%d:%s", len(chain.stages)+1, strings.TrimPrefix(reflect.TypeOf(transformer).String(), "main."))
+		chain.Stage(name, transformer)
+	}
+	return chain
+}
+
+// Stage adds a named stage at the end of the chain.
+func (chain *ChainTransformer) Stage(name string, transformer Transformer) *ChainTransformer {
+	chain.stages = append(chain.stages, chainStage{name: name, transformer: transformer})
+	return chain
+}
+
+// When makes the last stage conditional, it only runs when the condition holds for its input, otherwise the input is
+// passed on unchanged.
+func (chain *ChainTransformer) When(condition Predicate) *ChainTransformer {
+	if len(chain.stages) > 0 {
+		chain.stages[len(chain.stages)-1].when = condition
+	}
+	return chain
+}
+
+// Skip disables the stages by name, the input is passed on unchanged.
+func (chain *ChainTransformer) Skip(names ...string) *ChainTransformer {
+	if chain.skipped == nil {
+		chain.skipped = make(map[string]bool)
+	}
+	for _, name := range names {
+		chain.skipped[name] = true
+	}
+	return chain
+}
+
+// Stages returns the names of the stages in order.
+func (chain *ChainTransformer) Stages() []string {
+	names := make([]string, len(chain.stages))
+	for i, stage := range chain.stages {
+		names[i] = stage.name
+	}
+	return names
+}
+
+// Transform runs the stages in order, the first error stops the chain and is returned as a ChainError.
+func (chain *ChainTransformer) Transform(input interface{}) (interface{}, error) {
+	for name := range chain.skipped {
+		if !chain.hasStage(name) {
+			return nil, fmt.Errorf("ChainTransformer: unknown stage '%s' skipped", name)
+		}
+	}
+	value := input
+	for _, stage := range chain.stages {
+		if chain.skipped[stage.name] || (stage.when != nil && !stage.when(value)) {
+			continue
+		}
+		output, err := stage.transformer.Transform(value)
+		if err != nil {
+			return nil, &ChainError{Stage: stage.name, InputSize: inputSize(value), Err: err}
+		}
+		value = output
+	}
+	return value, nil
+}
+
+func (chain *ChainTransformer) hasStage(name string) bool {
+	for _, stage := range chain.stages {
+		if stage.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ChainError is the error of a stage of a chain.
+type ChainError struct {
+	Stage string
+	// The number of elements of the input of the stage, -1 if the input is not a list or a map
+	InputSize int
+	Err       error
+}
+
+func (e *ChainError) Error() string {
+	if e.InputSize < 0 {
+		return fmt.Sprintf("stage '%s' failed: %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("stage '%s' failed on %d elements: %v", e.Stage, e.InputSize, e.Err)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+// inputSize returns the number of elements of a list or a map, -1 otherwise.
+func inputSize(input interface{}) int {
+	if input == nil {
+		return -1
+	}
+	switch reflect.TypeOf(input).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return reflect.ValueOf(input).Len()
+	}
+	return -1
+}
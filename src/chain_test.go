@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainTransformer_Transform(t *testing.T) {
+	rename := ListMappingTransformer{mapping: map[string]string{"foo": "Foo", "drop": ""}}
+	sort := ListStringSortTransformer{mapper: func(input interface{}) string { return input.(string) }}
+	failing := Func[[]interface{}, []interface{}](func(input []interface{}) ([]interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	notEmpty := func(input interface{}) bool { return inputSize(input) > 0 }
+	tests := []struct {
+		name    string
+		chain   *ChainTransformer
+		input   interface{}
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name:  "stages in order",
+			chain: Chain(rename, sort),
+			input: []string{"foo", "bar", "drop"},
+			want:  []interface{}{"Foo", "bar"},
+		},
+		{
+			name:  "typed stages",
+			chain: Chain(Map(strings.ToUpper), Filter(func(s string) bool { return s != "BAR" })),
+			input: []interface{}{"foo", "bar"},
+			want:  []string{"FOO"},
+		},
+		{
+			name:  "skipped stage",
+			chain: Chain().Stage("rename", rename).Stage("sort", sort).Skip("sort"),
+			input: []string{"foo", "bar"},
+			want:  []interface{}{"Foo", "bar"},
+		},
+		{
+			name:  "condition",
+			chain: Chain().Stage("fail", failing).When(notEmpty),
+			input: []interface{}{},
+			want:  []interface{}{},
+		},
+		{
+			name:    "failed stage",
+			chain:   Chain().Stage("rename", rename).Stage("fail", failing).When(notEmpty),
+			input:   []string{"foo", "bar", "drop"},
+			wantErr: "stage 'fail' failed on 2 elements: boom",
+		},
+		{
+			name:    "default names",
+			chain:   Chain(rename, failing),
+			input:   []string{"foo"},
+			wantErr: "stage '2:Func[[]interface {},[]interface {}]' failed on 1 elements",
+		},
+		{
+			name:    "nested chain",
+			chain:   Chain().Stage("features", Chain().Stage("rename", rename).Stage("fail", failing)),
+			input:   []string{"foo"},
+			wantErr: "stage 'features' failed on 1 elements: stage 'fail' failed on 1 elements: boom",
+		},
+		{
+			name:    "unknown skipped stage",
+			chain:   Chain(rename).Skip("sort"),
+			input:   []string{"foo"},
+			wantErr: "unknown stage 'sort'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.chain.Transform(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Transform() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainError(t *testing.T) {
+	cause := errors.New("boom")
+	_, err := Chain().Stage("fail", Func[string, string](func(string) (string, error) { return "", cause })).Transform("input")
+	var chainError *ChainError
+	if !errors.As(err, &chainError) || chainError.Stage != "fail" || chainError.InputSize != -1 {
+		t.Fatalf("Transform() error = %#v, want a ChainError of stage fail", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("Transform() error does not wrap the cause")
+	}
+	if got := Chain(Map(strings.ToUpper)).Stage("sort", nil).Stages(); !reflect.DeepEqual(got, []string{"1:Func[[]string,[]string]", "sort"}) {
+		t.Errorf("Stages() = %v", got)
+	}
+}
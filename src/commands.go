@@ -0,0 +1,568 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// RenderCommand renders the templates of the selected feature sets, it's the default command.
+type RenderCommand struct {
+	// Parses the arguments again over the project file, nil if there is none to follow
+	reload func() (Args, error)
+}
+
+func (command RenderCommand) Run(w io.Writer, logger *slog.Logger, args Args) error {
+	if args.DryRun {
+		return dryRun(w, logger, args)
+	}
+	if args.Watch {
+		// every change would report the feature sets it renders again
+		if args.Report != "" {
+			return errors.New("--report can't be used with --watch")
+		}
+		watcher := NewWatcher(w, logger, args)
+		watcher.reload = command.reload
+		return watcher.RunUntilSignal()
+	}
+	if args.OutputDir != "" && !args.NoCache {
+		return renderCached(w, logger, args)
+	}
+	context, err := runPipeline(args, nil, logger)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	featureSets, err := selectFeatureSets(context)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	// 9. For each feature set, render the features
+	failures := make([]error, 0)
+	for _, result := range renderFeatureSets(context, featureSets) {
+		if _, err := writeFeatureSet(w, context, result); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return writeReport(context, errors.Join(failures...))
+}
+
+// renderCached renders the feature sets whose inputs changed since they were last rendered, see RenderCache.
+func renderCached(w io.Writer, logger *slog.Logger, args Args) error {
+	tracker := NewFileTracker()
+	context, err := runPipeline(args, tracker, logger)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	featureSets, err := selectFeatureSets(context)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	inputs := cacheInputs(tracker.Files(), context["properties"].([]interface{}))
+	cache := OpenRenderCache(args)
+	stale := make([]string, 0, len(featureSets))
+	for _, featureSet := range featureSets {
+		if !cache.Fresh(featureSet, inputs, args.OutputDir) {
+			stale = append(stale, featureSet)
+		}
+	}
+	results := renderFeatureSets(context, stale)
+	// the outputs of the cached feature sets are still claimed
+	owners := make(map[string]string)
+	for _, featureSet := range featureSets {
+		if !slices.Contains(stale, featureSet) {
+			for file := range cache.Outputs(featureSet) {
+				owners[file] = "feature set " + featureSet
+			}
+		}
+	}
+	claimOutputs(results, owners)
+	failures := make([]error, 0)
+	for _, featureSet := range featureSets {
+		i := slices.Index(stale, featureSet)
+		if i < 0 {
+			contextReport(context).addFeatureSet(FeatureSetReport{Name: featureSet, Cached: true})
+			outputs := cache.Outputs(featureSet)
+			for _, file := range slices.Sorted(maps.Keys(outputs)) {
+				contextReport(context).addFileHash(featureSet, args.OutputDir, file, outputs[file], false)
+			}
+			fmt.Fprintf(w, "feature set %s: cached\n", featureSet)
+			continue
+		}
+		written, err := writeFeatureSet(w, context, results[i])
+		if err != nil {
+			cache.Forget(featureSet)
+			failures = append(failures, err)
+			continue
+		}
+		cache.Store(featureSet, inputs, results[i].templates, results[i].outputs)
+		fmt.Fprintf(w, "feature set %s: rendered, %d of %d files written\n", featureSet, written, len(results[i].outputs))
+	}
+	fmt.Fprintf(w, "cache: %d hits, %d misses\n", cache.Hits, cache.Misses)
+	return writeReport(context, errors.Join(append(failures, cache.Save())...))
+}
+
+// dryRun runs the pipeline steps, the context is dumped with --dump-after or --dump-context, and prints the feature
+// sets that would be rendered.
+func dryRun(w io.Writer, logger *slog.Logger, args Args) error {
+	context, err := runPipeline(args, nil, logger)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	featureSets, err := selectFeatureSets(context)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	fmt.Fprintf(w, "dry run, %d feature sets not rendered: %s\n", len(featureSets), strings.Join(featureSets, ", "))
+	return writeReport(context, nil)
+}
+
+// ValidateCommand runs every step and renders the feature sets without writing the outputs. The outputs are validated
+// by their extension unless the front-matter says otherwise. Without --feature-set every feature set is validated.
+type ValidateCommand struct{}
+
+func (command ValidateCommand) Run(w io.Writer, logger *slog.Logger, args Args) error {
+	if len(args.FeatureSet) == 0 {
+		args.AllFeatureSets = true
+	}
+	args.ValidateOutput = true
+	if args.DryRun {
+		return dryRun(w, logger, args)
+	}
+	context, err := runPipeline(args, nil, logger)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	featureSets, err := selectFeatureSets(context)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	failures := make([]error, 0)
+	for _, result := range renderFeatureSets(context, featureSets) {
+		if result.err != nil {
+			failures = append(failures, result.err)
+			continue
+		}
+		fmt.Fprintf(w, "feature set %s: %d outputs ok\n", result.featureSet, len(result.outputs))
+	}
+	return writeReport(context, errors.Join(failures...))
+}
+
+// writeReport writes the report of the run with --report and returns the error of the run, or the one writing the
+// report.
+func writeReport(context map[string]interface{}, err error) error {
+	file := context["args"].(Args).Report
+	if reportErr := contextReport(context).Write(file, context, err); reportErr != nil {
+		return errors.Join(err, fmt.Errorf("report %s: %w", file, reportErr))
+	}
+	return err
+}
+
+// ListCommand prints the resolved features, feature sets or properties.
+type ListCommand struct {
+	What string `arg:"positional,required" help:"features, sets or properties"`
+}
+
+func (command ListCommand) Run(w io.Writer, logger *slog.Logger, args Args) error {
+	context, err := runPipeline(args, nil, logger)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	return writeReport(context, catch(func() {
+		switch command.What {
+		case "features":
+			listFeatures(w, context)
+		case "sets":
+			listFeatureSets(w, context)
+		case "properties":
+			listProperties(w, context)
+		default:
+			panic(fmt.Sprintf("unknown list '%s', use features, sets or properties", command.What))
+		}
+	}))
+}
+
+// listFeatures prints the features in order with their feature sets.
+func listFeatures(w io.Writer, context map[string]interface{}) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	keyPath := groupKeyPath(context["args"].(Args))
+	for _, feature := range context["features"].([]interface{}) {
+		fmt.Fprintf(tw, "%v\t%s\n", StringMapMapper("Name")(feature), strings.Join(featureSetsOf(feature, keyPath), ", "))
+	}
+	_ = tw.Flush()
+}
+
+// listFeatureSets prints the feature sets by name with their features in priority order.
+func listFeatureSets(w io.Writer, context map[string]interface{}) {
+	featureSets := make([]string, 0)
+	for featureSet := range context["feature-sets"].(map[string][]interface{}) {
+		featureSets = append(featureSets, featureSet)
+	}
+	slices.Sort(featureSets)
+	for _, featureSet := range featureSets {
+		names := make([]string, 0)
+		for _, feature := range sortedFeatureSet(context, featureSet) {
+			names = append(names, fmt.Sprint(StringMapMapper("Name")(feature)))
+		}
+		fmt.Fprintf(w, "%s: %s\n", featureSet, strings.Join(names, ", "))
+	}
+}
+
+// listProperties prints the merged properties by key.
+func listProperties(w io.Writer, context map[string]interface{}) {
+	merged := PropertiesLookup{properties: context["properties"].([]interface{})}.Merged()
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s = %v\n", key, merged[key])
+	}
+}
+
+// ExplainCommand follows a feature through the steps: the lines of the feature lists naming it, the renames, the
+// filters keeping or dropping it, the config entry it expanded to and its position in its feature sets.
+type ExplainCommand struct {
+	Feature string `arg:"positional,required" help:"the feature name, as in the feature lists or after the mapping"`
+}
+
+func (command ExplainCommand) Run(w io.Writer, logger *slog.Logger, args Args) error {
+	context, err := runPipeline(args, nil, logger)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	return writeReport(context, catch(func() {
+		explainFeature(w, context, command.Feature)
+	}))
+}
+
+// explainFeature prints the trace of the feature, named by a raw name of the feature lists or by its final name.
+// A line starting with dropped tells the step leaving it out.
+func explainFeature(w io.Writer, context map[string]interface{}, name string) {
+	args := context["args"].(Args)
+	fmt.Fprintf(w, "%s\n", name)
+	origins, _ := context["feature-origins"].([]FeatureOrigin)
+	// the removed names are mapped too, to find the lines removing the feature
+	rawNames := slices.Clone(context["raw-features"].([]string))
+	for _, origin := range origins {
+		if !slices.Contains(rawNames, origin.Name) {
+			rawNames = append(rawNames, origin.Name)
+		}
+	}
+	report := MappingReport{}
+	mapping := featureNameMapping(context).(ListMappingTransformer)
+	mapping.report = &report
+	// the warnings of the mapping are logged once, by the transform step
+	mapping.warnings = ignoredWarnings()
+	if _, err := mapping.Transform(rawNames); err != nil {
+		panic(err)
+	}
+	finalName := func(raw string) string {
+		if slices.Contains(report.Dropped, raw) {
+			return ""
+		}
+		if renamed, ok := report.Renamed[raw]; ok {
+			return renamed[len(renamed)-1]
+		}
+		return raw
+	}
+	target := name
+	if slices.Contains(rawNames, name) {
+		target = finalName(name)
+	}
+	matches := slices.DeleteFunc(slices.Clone(rawNames), func(raw string) bool {
+		return raw != name && (target == "" || finalName(raw) != target)
+	})
+	listed := false
+	for _, origin := range origins {
+		if !slices.Contains(matches, origin.Name) {
+			continue
+		}
+		if origin.Removed {
+			fmt.Fprintf(w, "  removed at %s: !%s\n", origin, origin.Name)
+		} else {
+			fmt.Fprintf(w, "  listed at %s: %s\n", origin, origin.Name)
+			listed = true
+		}
+	}
+	if !listed {
+		fmt.Fprintf(w, "  listed: no feature list names it\n")
+		if _, ok := context["config"].(map[interface{}]interface{})[target]; ok {
+			fmt.Fprintf(w, "  configured in %s but not listed, it's not rendered\n", args.ConfigFile)
+		} else {
+			fmt.Fprintf(w, "  not found in %s%s\n", args.ConfigFile, didYouMean(target, configKeys(context)))
+		}
+		return
+	}
+	for _, raw := range matches {
+		if renamed, ok := report.Renamed[raw]; ok {
+			fmt.Fprintf(w, "  renamed: %s\n", strings.Join(renamed, " -> "))
+		}
+	}
+	for _, conflict := range report.Conflicts {
+		if slices.Contains(matches, conflict.Input) {
+			fmt.Fprintf(w, "  mapping conflict: %s matches %v, '%s' used\n", conflict.Input, conflict.Keys, conflict.Keys[0])
+		}
+	}
+	if target == "" {
+		fmt.Fprintf(w, "  dropped: mapped to an empty name by %s\n", args.FeatureMappingFile)
+		return
+	}
+	mapped, err := mapping.Transform(context["raw-features"])
+	if err != nil {
+		panic(err)
+	}
+	count := 0
+	for _, feature := range mapped.([]interface{}) {
+		if feature == target {
+			count++
+		}
+	}
+	if count == 0 {
+		fmt.Fprintf(w, "  dropped: removed by the feature lists\n")
+		return
+	}
+	if count > 1 {
+		fmt.Fprintf(w, "  duplicates: listed %d times, kept once by the %s duplicate policy\n", count, args.DuplicatePolicy)
+	}
+	if excluded, ok := context["excluded-features"].([]interface{}); ok {
+		if slices.Contains(excluded, interface{}(target)) {
+			fmt.Fprintf(w, "  dropped: excluded by the exclude feature lists\n")
+			return
+		}
+		fmt.Fprintf(w, "  exclude: kept, not in the exclude feature lists\n")
+	}
+	if _, ok := context["config"].(map[interface{}]interface{})[target]; !ok {
+		fmt.Fprintf(w, "  dropped: not found in %s%s\n", args.ConfigFile, didYouMean(target, configKeys(context)))
+		return
+	}
+	feature := findFeature(context["features"].([]interface{}), target)
+	if feature == nil {
+		fmt.Fprintf(w, "  dropped: not in the features\n")
+		return
+	}
+	// the filters of the templates match the feature as it's rendered
+	rendered := feature
+	if m, ok := feature.(interface{ Map() map[string]interface{} }); ok {
+		feature = m.Map()
+	}
+	data, err := yaml.Marshal(feature)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(w, "  config:\n")
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+	if rows, ok := context["enrich"].([]map[string]interface{}); ok {
+		matched := slices.ContainsFunc(rows, func(row map[string]interface{}) bool {
+			return fmt.Sprint(row[args.EnrichKey]) == target
+		})
+		if matched {
+			fmt.Fprintf(w, "  enrich: row of %s merged\n", args.EnrichFile)
+		} else {
+			fmt.Fprintf(w, "  enrich: no row of %s matches\n", args.EnrichFile)
+		}
+	}
+	featureSets := featureSetsOf(feature, groupKeyPath(args))
+	if len(featureSets) == 0 {
+		fmt.Fprintf(w, "  feature sets: none, it's not rendered\n")
+	}
+	for _, featureSet := range featureSets {
+		features := sortedFeatureSet(context, featureSet)
+		position := slices.IndexFunc(features, func(f interface{}) bool { return StringMapMapper("Name")(f) == target })
+		fmt.Fprintf(w, "  feature set %s: %d of %d, %s\n", featureSet, position+1, len(features), featureSetSelection(args, featureSet))
+		explainTemplateFilters(w, args, featureSet, rendered)
+	}
+}
+
+// explainTemplateFilters prints for each template of the feature set whether the filter of its front-matter keeps the
+// feature. Nothing is printed without a template dir or templates, the render reports the missing templates.
+func explainTemplateFilters(w io.Writer, args Args, featureSet string, feature interface{}) {
+	if args.TemplateDir == "" {
+		return
+	}
+	loader := TemplateLoader{templates: os.DirFS(args.TemplateDir)}
+	files, err := featureSetTemplates(loader.templates, featureSet)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		frontMatter, _, err := loader.read(file)
+		if err != nil {
+			panic(err)
+		}
+		kept, err := filterFeatures([]interface{}{feature}, frontMatter.Filter)
+		if err != nil {
+			panic(err)
+		}
+		if len(kept.([]interface{})) == 0 {
+			fmt.Fprintf(w, "    template %s: dropped by filter %s\n", file, formatFilter(frontMatter.Filter))
+		} else {
+			fmt.Fprintf(w, "    template %s: kept\n", file)
+		}
+	}
+}
+
+// formatFilter formats the filter of a front-matter like its YAML flow style, e.g. {in: A, tier: web}.
+func formatFilter(filter map[string]interface{}) string {
+	pairs := make([]string, 0, len(filter))
+	for _, key := range slices.Sorted(maps.Keys(filter)) {
+		pairs = append(pairs, fmt.Sprintf("%s: %v", key, filter[key]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// configKeys returns the feature names of the config file.
+func configKeys(context map[string]interface{}) []string {
+	keys := make([]string, 0)
+	for key := range context["config"].(map[interface{}]interface{}) {
+		keys = append(keys, fmt.Sprint(key))
+	}
+	return keys
+}
+
+// featureSetSelection tells if the arguments select the feature set for rendering.
+func featureSetSelection(args Args, featureSet string) string {
+	switch {
+	case args.AllFeatureSets:
+		return "selected by --all-feature-sets"
+	case slices.Contains(args.FeatureSet, featureSet):
+		return "selected by --feature-set"
+	}
+	return "not selected by --feature-set, not rendered"
+}
+
+// GraphCommand prints the dependencies of the features, as text or as a Graphviz dot graph.
+type GraphCommand struct {
+	Format string `arg:"--format" default:"text" help:"text or dot"`
+}
+
+func (command GraphCommand) Run(w io.Writer, logger *slog.Logger, args Args) error {
+	context, err := runPipeline(args, nil, logger)
+	if err != nil {
+		return writeReport(context, err)
+	}
+	return writeReport(context, catch(func() {
+		switch command.Format {
+		case "text", "":
+			graphText(w, context["features"].([]interface{}))
+		case "dot":
+			graphDot(w, context["features"].([]interface{}))
+		default:
+			panic(fmt.Sprintf("unknown graph format '%s', use text or dot", command.Format))
+		}
+	}))
+}
+
+// featureDep is an edge of the dependency graph
+type featureDep struct {
+	feature  string
+	dep      string
+	instance string
+	in       []string
+}
+
+func (dep featureDep) label() string {
+	label := dep.instance
+	if len(dep.in) > 0 {
+		label = strings.TrimPrefix(fmt.Sprintf("%s, in %s", label, strings.Join(dep.in, ", ")), ", ")
+	}
+	return label
+}
+
+// featureDeps returns the dependencies of the feature in order.
+func featureDeps(feature interface{}) []featureDep {
+	name := fmt.Sprint(StringMapMapper("Name")(feature))
+	deps, err := toList(StringMapMapper("deps")(feature))
+	if err != nil {
+		panic(fmt.Sprintf("deps of %s: %v", name, err))
+	}
+	edges := make([]featureDep, 0, len(deps))
+	for _, dep := range deps {
+		edge := featureDep{feature: name, dep: MapValueStringMapper("name")(dep), instance: MapValueStringMapper("instance")(dep)}
+		if in := StringMapMapper("in")(dep); in != nil {
+			if list, err := toList(in); err == nil {
+				for _, item := range list {
+					edge.in = append(edge.in, fmt.Sprint(item))
+				}
+			} else {
+				edge.in = []string{fmt.Sprint(in)}
+			}
+		}
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+func graphText(w io.Writer, features []interface{}) {
+	for _, feature := range features {
+		deps := featureDeps(feature)
+		if len(deps) == 0 {
+			fmt.Fprintf(w, "%v\n", StringMapMapper("Name")(feature))
+		}
+		for _, dep := range deps {
+			if label := dep.label(); label != "" {
+				fmt.Fprintf(w, "%s -> %s (%s)\n", dep.feature, dep.dep, label)
+			} else {
+				fmt.Fprintf(w, "%s -> %s\n", dep.feature, dep.dep)
+			}
+		}
+	}
+}
+
+func graphDot(w io.Writer, features []interface{}) {
+	fmt.Fprintf(w, "digraph features {\n")
+	for _, feature := range features {
+		fmt.Fprintf(w, "  %q [shape=box];\n", fmt.Sprint(StringMapMapper("Name")(feature)))
+		for _, dep := range featureDeps(feature) {
+			fmt.Fprintf(w, "  %q -> %q [label=%q];\n", dep.feature, dep.dep, dep.label())
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// featureSetsOf returns the feature sets of the feature, one or many, named by the value at the key path.
+func featureSetsOf(feature interface{}, keyPath string) []string {
+	value := MapPathMapper(keyPath)(feature)
+	if value == nil {
+		return nil
+	}
+	if list, err := toList(value); err == nil {
+		featureSets := make([]string, len(list))
+		for i, item := range list {
+			featureSets[i] = fmt.Sprint(item)
+		}
+		return featureSets
+	}
+	return []string{fmt.Sprint(value)}
+}
+
+// sortedFeatureSet returns the features of the feature set in priority order.
+func sortedFeatureSet(context map[string]interface{}, featureSet string) []interface{} {
+	step := ListStringSortTransformer{
+		mapper: MapValueStringMapper("priority"),
+	}
+	value, err := step.Transform(context["feature-sets"].(map[string][]interface{})[featureSet])
+	if err != nil {
+		panic(err)
+	}
+	return value.([]interface{})
+}
+
+// findFeature returns the feature of the name, nil if there is none.
+func findFeature(features []interface{}, name string) interface{} {
+	for _, feature := range features {
+		if StringMapMapper("Name")(feature) == name {
+			return feature
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testCommandContext() map[string]interface{} {
+	foo := map[string]interface{}{
+		"Name":        "Foo",
+		"priority":    "A01",
+		"feature-set": "one",
+		"deps": []interface{}{
+			map[string]interface{}{"name": "Bar", "instance": "bar-A", "in": "A"},
+			map[string]interface{}{"name": "Baz"},
+		},
+	}
+	bar := map[string]interface{}{"Name": "Bar", "priority": "A02", "feature-set": []interface{}{"one", "two"}}
+	baz := Feature{Name: "Baz", Priority: "B01"}
+	return map[string]interface{}{
+		"args":         Args{ConfigFile: "config.yaml"},
+		"config":       map[interface{}]interface{}{"Foo": nil, "Bar": nil, "Baz": nil},
+		"raw-features": []string{"bar", "Foo", "Baz"},
+		"feature-origins": []FeatureOrigin{
+			{Name: "bar", Source: "features.txt", Line: 1},
+			{Name: "Foo", Source: "features.txt", Line: 2},
+			{Name: "Baz", Source: "@group base", Line: 1},
+		},
+		"feature-mapping": map[string]string{"bar": "Bar"},
+		"features":        []interface{}{bar, foo, baz},
+		"feature-sets": map[string][]interface{}{
+			"one": {bar, foo},
+			"two": {bar},
+		},
+		"properties": []interface{}{
+			map[string]interface{}{"b": "2", "a": "1"},
+			map[string]interface{}{"a": "3"},
+		},
+	}
+}
+
+func TestListCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		list func(w *bytes.Buffer, context map[string]interface{})
+		want string
+	}{
+		{
+			name: "features",
+			list: func(w *bytes.Buffer, context map[string]interface{}) { listFeatures(w, context) },
+			want: "Bar  one, two\nFoo  one\nBaz  \n",
+		},
+		{
+			name: "sets",
+			list: func(w *bytes.Buffer, context map[string]interface{}) { listFeatureSets(w, context) },
+			want: "one: Foo, Bar\ntwo: Bar\n",
+		},
+		{
+			name: "properties",
+			list: func(w *bytes.Buffer, context map[string]interface{}) { listProperties(w, context) },
+			want: "a = 1\nb = 2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			tt.list(w, testCommandContext())
+			if got := w.String(); got != tt.want {
+				t.Errorf("list %s = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainFeature(t *testing.T) {
+	templateDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(templateDir, "one"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	templates := map[string]string{
+		"one/all.tmpl": "",
+		"one/a.tmpl":   "{{/*---\nfilter:\n  priority: A02\n---*/}}\n",
+		"one/web.tmpl": "{{/*---\nfilter:\n  tier: web\n---*/}}\n",
+	}
+	for file, content := range templates {
+		if err := os.WriteFile(filepath.Join(templateDir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		feature string
+		// changes the context of the commands
+		change func(context map[string]interface{})
+		want   string
+	}{
+		{
+			name:    "renamed, by the final name",
+			feature: "Bar",
+			want: "Bar\n  listed at features.txt:1: bar\n  renamed: bar -> Bar\n" +
+				"  config:\n    Name: Bar\n    feature-set:\n        - one\n        - two\n    priority: A02\n" +
+				"  feature set one: 2 of 2, not selected by --feature-set, not rendered\n" +
+				"  feature set two: 1 of 1, not selected by --feature-set, not rendered\n",
+		},
+		{
+			name:    "filters of the templates",
+			feature: "Bar",
+			change: func(context map[string]interface{}) {
+				context["args"] = Args{ConfigFile: "config.yaml", TemplateDir: templateDir}
+			},
+			want: "Bar\n  listed at features.txt:1: bar\n  renamed: bar -> Bar\n" +
+				"  config:\n    Name: Bar\n    feature-set:\n        - one\n        - two\n    priority: A02\n" +
+				"  feature set one: 2 of 2, not selected by --feature-set, not rendered\n" +
+				"    template one/a.tmpl: kept\n    template one/all.tmpl: kept\n" +
+				"    template one/web.tmpl: dropped by filter {tier: web}\n" +
+				"  feature set two: 1 of 1, not selected by --feature-set, not rendered\n",
+		},
+		{
+			name:    "renamed, by the raw name",
+			feature: "bar",
+			change: func(context map[string]interface{}) {
+				context["args"] = Args{ConfigFile: "config.yaml", FeatureSet: []string{"two"}}
+			},
+			want: "bar\n  listed at features.txt:1: bar\n  renamed: bar -> Bar\n" +
+				"  config:\n    Name: Bar\n    feature-set:\n        - one\n        - two\n    priority: A02\n" +
+				"  feature set one: 2 of 2, not selected by --feature-set, not rendered\n" +
+				"  feature set two: 1 of 1, selected by --feature-set\n",
+		},
+		{
+			name:    "typed without feature set",
+			feature: "Baz",
+			want:    "Baz\n  listed at @group base, entry 1: Baz\n  config:\n    Name: Baz\n    priority: B01\n  feature sets: none, it's not rendered\n",
+		},
+		{
+			name:    "unknown",
+			feature: "Qux",
+			want:    "Qux\n  listed: no feature list names it\n  not found in config.yaml\n",
+		},
+		{
+			name:    "configured, not listed",
+			feature: "Baz",
+			change: func(context map[string]interface{}) {
+				context["raw-features"] = []string{"bar", "Foo"}
+				context["feature-origins"] = context["feature-origins"].([]FeatureOrigin)[:2]
+			},
+			want: "Baz\n  listed: no feature list names it\n  configured in config.yaml but not listed, it's not rendered\n",
+		},
+		{
+			name:    "removed",
+			feature: "Foo",
+			change: func(context map[string]interface{}) {
+				context["raw-features"] = []string{"bar", "Baz"}
+				context["feature-origins"] = append(context["feature-origins"].([]FeatureOrigin),
+					FeatureOrigin{Name: "Foo", Source: "local.txt", Line: 3, Removed: true})
+			},
+			want: "Foo\n  listed at features.txt:2: Foo\n  removed at local.txt:3: !Foo\n  dropped: removed by the feature lists\n",
+		},
+		{
+			name:    "mapped to an empty name",
+			feature: "bar",
+			change: func(context map[string]interface{}) {
+				context["feature-mapping"] = map[string]string{"bar": ""}
+				context["args"] = Args{ConfigFile: "config.yaml", FeatureMappingFile: "feature-rename.properties"}
+			},
+			want: "bar\n  listed at features.txt:1: bar\n  dropped: mapped to an empty name by feature-rename.properties\n",
+		},
+		{
+			name:    "duplicated and excluded",
+			feature: "Foo",
+			change: func(context map[string]interface{}) {
+				context["raw-features"] = []string{"bar", "Foo", "Baz", "Foo"}
+				context["feature-origins"] = append(context["feature-origins"].([]FeatureOrigin),
+					FeatureOrigin{Name: "Foo", Source: "local.txt", Line: 1})
+				context["excluded-features"] = []interface{}{"Foo"}
+				context["args"] = Args{ConfigFile: "config.yaml", DuplicatePolicy: "first"}
+			},
+			want: "Foo\n  listed at features.txt:2: Foo\n  listed at local.txt:1: Foo\n" +
+				"  duplicates: listed 2 times, kept once by the first duplicate policy\n  dropped: excluded by the exclude feature lists\n",
+		},
+		{
+			name:    "not in the config",
+			feature: "Qux",
+			change: func(context map[string]interface{}) {
+				context["raw-features"] = []string{"bar", "Foo", "Baz", "Qux"}
+				context["feature-origins"] = append(context["feature-origins"].([]FeatureOrigin),
+					FeatureOrigin{Name: "Qux", Source: "features.txt", Line: 4})
+			},
+			want: "Qux\n  listed at features.txt:4: Qux\n  dropped: not found in config.yaml\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			context := testCommandContext()
+			if tt.change != nil {
+				tt.change(context)
+			}
+			explainFeature(w, context, tt.feature)
+			if got := w.String(); got != tt.want {
+				t.Errorf("explainFeature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph(t *testing.T) {
+	features := testCommandContext()["features"].([]interface{})
+	w := &bytes.Buffer{}
+	graphText(w, features)
+	want := "Bar\nFoo -> Bar (bar-A, in A)\nFoo -> Baz\nBaz\n"
+	if got := w.String(); got != want {
+		t.Errorf("graphText() = %q, want %q", got, want)
+	}
+	w.Reset()
+	graphDot(w, features)
+	want = "digraph features {\n" +
+		"  \"Bar\" [shape=box];\n" +
+		"  \"Foo\" [shape=box];\n" +
+		"  \"Foo\" -> \"Bar\" [label=\"bar-A, in A\"];\n" +
+		"  \"Foo\" -> \"Baz\" [label=\"\"];\n" +
+		"  \"Baz\" [shape=box];\n" +
+		"}\n"
+	if got := w.String(); got != want {
+		t.Errorf("graphDot() = %q, want %q", got, want)
+	}
+}
+
+func TestCatch(t *testing.T) {
+	boom := errors.New("boom")
+	if err := catch(func() {}); err != nil {
+		t.Errorf("catch() = %v, want nil", err)
+	}
+	if err := catch(func() { panic(boom) }); !errors.Is(err, boom) {
+		t.Errorf("catch() = %v, want %v", err, boom)
+	}
+	if err := catch(func() { panic("message") }); err == nil || err.Error() != "message" {
+		t.Errorf("catch() = %v, want message", err)
+	}
+}
+
+func TestCommands_Report(t *testing.T) {
+	dir := t.TempDir()
+	args := Args{
+		ConfigDir:          "../samples/configs",
+		FeatureFile:        []string{"features.txt"},
+		FeatureMappingFile: "feature-rename.properties",
+		ConfigFile:         "config.yaml",
+		FeatureSet:         []string{"one"},
+		PropertyFiles:      []string{"one.properties"},
+		TemplateDir:        "../samples/templates",
+		OutputDir:          filepath.Join(dir, "out"),
+		CacheDir:           filepath.Join(dir, "cache"),
+		Report:             filepath.Join(dir, "report.json"),
+		WarningPolicy:      []string{"unused-config-key=ignore"},
+	}
+	readReport := func() map[string]interface{} {
+		t.Helper()
+		data, err := os.ReadFile(args.Report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report map[string]interface{}
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+	for _, written := range []bool{true, false} {
+		if err := (RenderCommand{}).Run(io.Discard, nil, args); err != nil {
+			t.Fatalf("render error = %v", err)
+		}
+		files := readReport()["files"].([]interface{})
+		if len(files) != 1 {
+			t.Fatalf("written %v: files = %v", written, files)
+		}
+		file := files[0].(map[string]interface{})
+		output, _ := os.ReadFile(filepath.Join(dir, "out/one"))
+		if file["path"] != filepath.Join(dir, "out/one") || file["written"] != written || file["sha256"] != hashBytes(output) {
+			t.Errorf("written %v: file = %v", written, file)
+		}
+	}
+
+	if err := (ListCommand{What: "sets"}).Run(io.Discard, nil, args); err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if steps := readReport()["steps"].([]interface{}); len(steps) != len(pipelineSteps) {
+		t.Errorf("list report steps = %v", steps)
+	}
+
+	args.Watch = true
+	if err := (RenderCommand{}).Run(io.Discard, nil, args); err == nil || err.Error() != "--report can't be used with --watch" {
+		t.Errorf("watch error = %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// The keys of the context holding the machinery of the run rather than data, they are not dumped
+var internalContextKeys = []string{"templates", "file-tracker", "logger", "warnings", "report", "property-usage"}
+
+// dumpStep returns the step after which the context is dumped, empty if it's not. --dump-context dumps it after the
+// last step.
+func dumpStep(args Args) (string, error) {
+	if args.DumpAfter == "" {
+		if args.DumpContext {
+			return pipelineSteps[len(pipelineSteps)-1].name, nil
+		}
+		return "", nil
+	}
+	names := make([]string, len(pipelineSteps))
+	for i, step := range pipelineSteps {
+		names[i] = step.name
+	}
+	if !slices.Contains(names, args.DumpAfter) {
+		return "", fmt.Errorf("unknown step '%s' for --dump-after, use %s", args.DumpAfter, strings.Join(names, ", "))
+	}
+	return args.DumpAfter, nil
+}
+
+// dumpContext writes the --dump-key keys of the context, all the data if none, as YAML or JSON to the --dump-file or
+// stdout.
+func dumpContext(context map[string]interface{}) error {
+	args := context["args"].(Args)
+	data, err := contextData(context, args.DumpKeys)
+	if err != nil {
+		return err
+	}
+	encoded, err := marshalDump(data, args.DumpFormat)
+	if err != nil {
+		return err
+	}
+	if args.DumpFile == "" {
+		_, err = os.Stdout.Write(encoded)
+		return err
+	}
+	return os.WriteFile(args.DumpFile, encoded, 0644)
+}
+
+// contextData returns the keys of the context, all but the internal ones if none. A key not in the context is an error
+// as the steps storing it didn't run yet.
+func contextData(context map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	if len(keys) == 0 {
+		data := maps.Clone(context)
+		for _, key := range internalContextKeys {
+			delete(data, key)
+		}
+		data["args"] = argsByFlag(data["args"].(Args))
+		return data, nil
+	}
+	data := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		value, ok := context[key]
+		if !ok || slices.Contains(internalContextKeys, key) {
+			available := slices.DeleteFunc(slices.Sorted(maps.Keys(context)), func(key string) bool {
+				return slices.Contains(internalContextKeys, key)
+			})
+			return nil, fmt.Errorf("key '%s' not in the context, use %s", key, strings.Join(available, ", "))
+		}
+		data[key] = value
+	}
+	if args, ok := data["args"].(Args); ok {
+		data["args"] = argsByFlag(args)
+	}
+	return data, nil
+}
+
+// argsByFlag returns the arguments keyed by the flag names without the dashes, as in the project file.
+func argsByFlag(args Args) map[string]interface{} {
+	rv := reflect.ValueOf(args)
+	values := make(map[string]interface{}, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		for _, option := range strings.Split(rv.Type().Field(i).Tag.Get("arg"), ",") {
+			if strings.HasPrefix(option, "--") {
+				values[strings.TrimPrefix(option, "--")] = rv.Field(i).Interface()
+			}
+		}
+	}
+	return values
+}
+
+// marshalDump encodes the data indented by 2 spaces with the map keys sorted.
+func marshalDump(data map[string]interface{}, format string) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	switch format {
+	case "yaml":
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(data); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	case "json":
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(normalizeKeys(data)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown dump format '%s', use yaml or json", format)
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDumpStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    Args
+		want    string
+		wantErr string
+	}{
+		{name: "no dump", args: Args{}, want: ""},
+		{name: "after a step", args: Args{DumpAfter: "mapping"}, want: "mapping"},
+		{name: "context, after the last step", args: Args{DumpContext: true}, want: "group"},
+		{name: "step wins over context", args: Args{DumpAfter: "config", DumpContext: true}, want: "config"},
+		{name: "unknown step", args: Args{DumpAfter: "render"},
+			wantErr: "unknown step 'render' for --dump-after, use config, features, mapping, transform, properties, templates, group"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dumpStep(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("dumpStep() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("dumpStep() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDumpContext(t *testing.T) {
+	context := map[string]interface{}{
+		"config":          map[interface{}]interface{}{"Foo": map[string]interface{}{"priority": "A01", "deps": []interface{}{"Bar"}}},
+		"feature-mapping": map[string]string{"bar": "Bar"},
+		"raw-features":    []string{"bar", "Foo"},
+		"warnings":        &Warnings{},
+		"property-usage":  &PropertyUsage{},
+	}
+	tests := []struct {
+		name    string
+		args    Args
+		want    string
+		wantErr string
+	}{
+		{
+			name: "selected keys as YAML",
+			args: Args{DumpKeys: []string{"raw-features", "config"}, DumpFormat: "yaml"},
+			want: "config:\n  Foo:\n    deps:\n      - Bar\n    priority: A01\nraw-features:\n  - bar\n  - Foo\n",
+		},
+		{
+			name: "selected keys as JSON",
+			args: Args{DumpKeys: []string{"config", "feature-mapping"}, DumpFormat: "json"},
+			want: "{\n  \"config\": {\n    \"Foo\": {\n      \"deps\": [\n        \"Bar\"\n      ],\n      \"priority\": \"A01\"\n    }\n  },\n" +
+				"  \"feature-mapping\": {\n    \"bar\": \"Bar\"\n  }\n}\n",
+		},
+		{
+			name:    "key not in the context",
+			args:    Args{DumpKeys: []string{"features"}, DumpFormat: "yaml"},
+			wantErr: "key 'features' not in the context, use args, config, feature-mapping, raw-features",
+		},
+		{
+			name:    "internal key",
+			args:    Args{DumpKeys: []string{"warnings"}, DumpFormat: "yaml"},
+			wantErr: "key 'warnings' not in the context, use args, config, feature-mapping, raw-features",
+		},
+		{
+			name:    "unknown format",
+			args:    Args{DumpFormat: "xml"},
+			wantErr: "unknown dump format 'xml', use yaml or json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "dump")
+			tt.args.DumpFile = file
+			context["args"] = tt.args
+			err := dumpContext(context)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("dumpContext() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dumpContext() error = %v", err)
+			}
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("dump = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The typed features are dumped by their config.yaml keys in both formats.
+func TestMarshalDump_TypedFeatures(t *testing.T) {
+	feature := Feature{
+		Name:       "Foo",
+		FeatureSet: StringList{"one"},
+		Deps:       []FeatureDep{{Name: "Bar", In: StringList{"A"}}},
+		Extras:     map[string]interface{}{"x-owner": "web"},
+	}
+	data := map[string]interface{}{"features": []interface{}{feature}}
+	want := map[string]interface{}{"features": []interface{}{map[string]interface{}{
+		"Name": "Foo", "feature-set": []interface{}{"one"}, "x-owner": "web",
+		"deps": []interface{}{map[string]interface{}{"name": "Bar", "in": []interface{}{"A"}}},
+	}}}
+	for _, format := range []string{"yaml", "json"} {
+		encoded, err := marshalDump(data, format)
+		if err != nil {
+			t.Fatalf("marshalDump(%s) error = %v", format, err)
+		}
+		got := map[string]interface{}{}
+		if err := yaml.Unmarshal(encoded, &got); err != nil {
+			t.Fatalf("%s dump: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s dump = %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestArgsByFlag(t *testing.T) {
+	values := argsByFlag(Args{ConfigDir: "configs", ChainMapping: true, FeatureFile: []string{"features.txt"}})
+	if values["config-dir"] != "configs" || values["chain-feature-mapping"] != true {
+		t.Errorf("argsByFlag() = %v", values)
+	}
+	if files, ok := values["feature-file"].([]string); !ok || len(files) != 1 {
+		t.Errorf("argsByFlag() feature-file = %v", values["feature-file"])
+	}
+	if _, ok := values["ConfigDir"]; ok {
+		t.Errorf("argsByFlag() has the field names: %v", values)
+	}
+}
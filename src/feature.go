@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// The prefix of the custom keys of a feature, they are kept in Extras
+const extraKeyPrefix = "x-"
+
+// Feature is the typed model of a feature of config.yaml. The keys other than the typed fields are kept in Extras,
+// they must start with x- unless allowed by the decoder, e.g. the columns of the enrich file.
+type Feature struct {
+	Name       string                 `yaml:"Name,omitempty"`
+	Priority   string                 `yaml:"priority,omitempty"`
+	FeatureSet StringList             `yaml:"feature-set,omitempty"`
+	Parameters []FeatureParameter     `yaml:"parameters,omitempty"`
+	Deps       []FeatureDep           `yaml:"deps,omitempty"`
+	Properties []string               `yaml:"properties,omitempty"`
+	Extras     map[string]interface{} `yaml:",inline"`
+}
+
+// FeatureParameter is a parameter of a feature, the value is read from the property.
+type FeatureParameter struct {
+	Name     string                 `yaml:"name"`
+	Property string                 `yaml:"property,omitempty"`
+	Extras   map[string]interface{} `yaml:",inline"`
+}
+
+// FeatureDep is a dependency of a feature, In lists the config identifiers it applies to.
+type FeatureDep struct {
+	Name     string                 `yaml:"name"`
+	Instance string                 `yaml:"instance,omitempty"`
+	In       StringList             `yaml:"in,omitempty"`
+	Extras   map[string]interface{} `yaml:",inline"`
+}
+
+// Value returns the value of the key, a typed field by its config.yaml key or an extra, nil if not set.
+func (feature Feature) Value(key string) interface{} {
+	return recordValue(feature, feature.Extras, key)
+}
+
+// Map returns the generic map view of the feature, the same map an untyped feature would be.
+func (feature Feature) Map() map[string]interface{} {
+	return recordMap(feature)
+}
+
+// MarshalJSON encodes the feature by its config.yaml keys like the YAML encoding, the extras inline.
+func (feature Feature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(feature.Map())
+}
+
+func (parameter FeatureParameter) Value(key string) interface{} {
+	return recordValue(parameter, parameter.Extras, key)
+}
+
+func (parameter FeatureParameter) Map() map[string]interface{} {
+	return recordMap(parameter)
+}
+
+func (parameter FeatureParameter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(parameter.Map())
+}
+
+func (dep FeatureDep) Value(key string) interface{} {
+	return recordValue(dep, dep.Extras, key)
+}
+
+func (dep FeatureDep) Map() map[string]interface{} {
+	return recordMap(dep)
+}
+
+func (dep FeatureDep) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dep.Map())
+}
+
+// recordValue finds the field of the record by its yaml key, or the key in the extras. Zero fields are nil.
+func recordValue(record interface{}, extras map[string]interface{}, key string) interface{} {
+	rv := reflect.ValueOf(record)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name == key {
+			if rv.Field(i).IsZero() {
+				return nil
+			}
+			return rv.Field(i).Interface()
+		}
+	}
+	return extras[key]
+}
+
+// recordMap converts the record to a generic map by its yaml keys.
+func recordMap(record interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	data, err := yaml.Marshal(record)
+	if err == nil {
+		err = yaml.Unmarshal(data, &m)
+	}
+	if err != nil {
+		// the records only hold YAML values
+		panic(err)
+	}
+	return m
+}
+
+// FeatureDecodeTransformer decodes the untyped features into the typed Feature. The values are type checked and the
+// keys that are neither typed fields, nor x- keys, nor one of the extraKeys are errors.
+type FeatureDecodeTransformer struct {
+	extraKeys []string
+}
+
+// Transform decodes every feature of the list, the result is a []interface{} of Feature.
+func (config FeatureDecodeTransformer) Transform(input interface{}) (interface{}, error) {
+	if input == nil {
+		return nil, errors.New("FeatureDecodeTransformer: Input is nil")
+	}
+	if reflect.TypeOf(input).Kind() != reflect.Slice {
+		return nil, errors.New("FeatureDecodeTransformer: Input is not a list")
+	}
+	listV := reflect.ValueOf(input)
+	records := make([]interface{}, 0, listV.Len())
+	for i := 0; i < listV.Len(); i++ {
+		el := listV.Index(i).Interface()
+		feature, err := config.decode(el)
+		if err != nil {
+			name := StringMapMapper("Name")(el)
+			return nil, fmt.Errorf("FeatureDecodeTransformer: feature '%v': %w", name, err)
+		}
+		records = append(records, feature)
+	}
+	return records, nil
+}
+
+func (config FeatureDecodeTransformer) decode(input interface{}) (Feature, error) {
+	feature := Feature{}
+	if input == nil || reflect.TypeOf(input).Kind() != reflect.Map {
+		return feature, errors.New("not a map")
+	}
+	data, err := yaml.Marshal(input)
+	if err != nil {
+		return feature, err
+	}
+	if err := yaml.Unmarshal(data, &feature); err != nil {
+		return feature, errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	if err := config.checkExtras(feature.Extras, config.extraKeys, ""); err != nil {
+		return feature, err
+	}
+	for i, parameter := range feature.Parameters {
+		if parameter.Name == "" {
+			return feature, fmt.Errorf("parameters[%d]: name is required", i)
+		}
+		if err := config.checkExtras(parameter.Extras, nil, fmt.Sprintf("parameters[%d].", i)); err != nil {
+			return feature, err
+		}
+	}
+	for i, dep := range feature.Deps {
+		if dep.Name == "" {
+			return feature, fmt.Errorf("deps[%d]: name is required", i)
+		}
+		if err := config.checkExtras(dep.Extras, nil, fmt.Sprintf("deps[%d].", i)); err != nil {
+			return feature, err
+		}
+	}
+	return feature, nil
+}
+
+// checkExtras returns an error for the first unknown key in name order.
+func (config FeatureDecodeTransformer) checkExtras(extras map[string]interface{}, allowed []string, path string) error {
+	keys := make([]string, 0, len(extras))
+	for key := range extras {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, extraKeyPrefix) && !slices.Contains(allowed, key) {
+			return fmt.Errorf("%sunknown field '%s', custom fields must start with %s", path, key, extraKeyPrefix)
+		}
+	}
+	return nil
+}
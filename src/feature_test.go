@@ -0,0 +1,140 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFeatureDecodeTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name      string
+		extraKeys []string
+		input     interface{}
+		want      []interface{}
+		wantErr   string
+	}{
+		{
+			name: "typed",
+			input: []interface{}{
+				map[string]interface{}{
+					"Name":        "Foo",
+					"priority":    10,
+					"feature-set": "one",
+					"parameters":  []interface{}{map[string]interface{}{"name": "p", "property": "prop"}},
+					"deps":        []interface{}{map[string]interface{}{"name": "d", "instance": "d-A", "in": "A"}},
+					"properties":  []interface{}{"x"},
+					"x-owner":     "team",
+				},
+				map[interface{}]interface{}{"Name": "Bar", "feature-set": []interface{}{"one", "two"}},
+			},
+			want: []interface{}{
+				Feature{
+					Name:       "Foo",
+					Priority:   "10",
+					FeatureSet: StringList{"one"},
+					Parameters: []FeatureParameter{{Name: "p", Property: "prop"}},
+					Deps:       []FeatureDep{{Name: "d", Instance: "d-A", In: StringList{"A"}}},
+					Properties: []string{"x"},
+					Extras:     map[string]interface{}{"x-owner": "team"},
+				},
+				Feature{Name: "Bar", FeatureSet: StringList{"one", "two"}},
+			},
+		},
+		{
+			name:      "extra keys",
+			extraKeys: []string{"Owner"},
+			input:     []interface{}{map[string]interface{}{"Name": "Foo", "Owner": "team"}},
+			want:      []interface{}{Feature{Name: "Foo", Extras: map[string]interface{}{"Owner": "team"}}},
+		},
+		{
+			name:    "unknown field",
+			input:   []interface{}{map[string]interface{}{"Name": "Foo", "feature_set": "one"}},
+			wantErr: "feature 'Foo': unknown field 'feature_set'",
+		},
+		{
+			name:    "unknown parameter field",
+			input:   []interface{}{map[string]interface{}{"Name": "Foo", "parameters": []interface{}{map[string]interface{}{"name": "p", "value": 1}}}},
+			wantErr: "parameters[0].unknown field 'value'",
+		},
+		{
+			name:    "wrong type",
+			input:   []interface{}{map[string]interface{}{"Name": "Foo", "properties": "x"}},
+			wantErr: "feature 'Foo': unmarshal errors",
+		},
+		{
+			name:    "dep without name",
+			input:   []interface{}{map[string]interface{}{"Name": "Foo", "deps": []interface{}{map[string]interface{}{"in": "A"}}}},
+			wantErr: "deps[0]: name is required",
+		},
+		{name: "not a list", input: "Foo", wantErr: "Input is not a list"},
+		{name: "nil", input: nil, wantErr: "Input is nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FeatureDecodeTransformer{extraKeys: tt.extraKeys}.Transform(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Transform() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeature_Value(t *testing.T) {
+	feature := Feature{
+		Name:       "Foo",
+		FeatureSet: StringList{"one"},
+		Deps:       []FeatureDep{{Name: "d", In: StringList{"A"}}},
+		Extras:     map[string]interface{}{"x-owner": "team"},
+	}
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{key: "Name", want: "Foo"},
+		{key: "feature-set", want: StringList{"one"}},
+		{key: "x-owner", want: "team"},
+		{key: "priority", want: nil},
+		{key: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := feature.Value(tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := MapPathMapper("x-owner")(feature); got != "team" {
+		t.Errorf("MapPathMapper() = %v, want team", got)
+	}
+	if !MapValueContainsPredicate("in", "A")(feature.Deps[0]) {
+		t.Errorf("MapValueContainsPredicate() = false, want true")
+	}
+}
+
+func TestFeature_Map(t *testing.T) {
+	feature := Feature{
+		Name:       "Foo",
+		Priority:   "A01",
+		Parameters: []FeatureParameter{{Name: "p", Property: "prop"}},
+		Extras:     map[string]interface{}{"x-owner": "team"},
+	}
+	want := map[string]interface{}{
+		"Name":       "Foo",
+		"priority":   "A01",
+		"parameters": []interface{}{map[string]interface{}{"name": "p", "property": "prop"}},
+		"x-owner":    "team",
+	}
+	if got := feature.Map(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Map() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileTracker records the files opened through its file systems, the watch mode polls them for changes. A file is
+// stamped before it's first opened, so a change made while it's read is a change since the stamp. A nil tracker does
+// not track, its file systems are plain os.DirFS.
+type FileTracker struct {
+	mu    sync.Mutex
+	files map[string]fileStamp
+}
+
+func NewFileTracker() *FileTracker {
+	return &FileTracker{files: make(map[string]fileStamp)}
+}
+
+// FS returns the file system of the dir, the files and the dirs opened are recorded by their path.
+func (tracker *FileTracker) FS(dir string) fs.FS {
+	if tracker == nil {
+		return os.DirFS(dir)
+	}
+	return trackingFS{fsys: os.DirFS(dir), dir: dir, tracker: tracker}
+}
+
+// Files returns the paths of the files opened so far in name order, the files not found included.
+func (tracker *FileTracker) Files() []string {
+	if tracker == nil {
+		return nil
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	files := make([]string, 0, len(tracker.files))
+	for file := range tracker.files {
+		files = append(files, file)
+	}
+	slices.Sort(files)
+	return files
+}
+
+// Stamps returns the stamps of the files opened so far, taken before they were first opened.
+func (tracker *FileTracker) Stamps() map[string]fileStamp {
+	if tracker == nil {
+		return nil
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return maps.Clone(tracker.files)
+}
+
+// addAll records the files read through another tracker, with the stamps it took.
+func (tracker *FileTracker) addAll(stamps map[string]fileStamp) {
+	if tracker == nil {
+		return
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	for file, stamp := range stamps {
+		if _, ok := tracker.files[file]; !ok {
+			tracker.files[file] = stamp
+		}
+	}
+}
+
+func (tracker *FileTracker) add(file string) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if _, ok := tracker.files[file]; !ok {
+		tracker.files[file] = stampFiles([]string{file})[file]
+	}
+}
+
+type trackingFS struct {
+	fsys    fs.FS
+	dir     string
+	tracker *FileTracker
+}
+
+func (t trackingFS) Open(name string) (fs.File, error) {
+	t.tracker.add(filepath.Join(t.dir, name))
+	return t.fsys.Open(name)
+}
+
+// fileTracker returns the tracker of the files read by the steps, nil when they are not tracked.
+func fileTracker(context map[string]interface{}) *FileTracker {
+	tracker, _ := context["file-tracker"].(*FileTracker)
+	return tracker
+}
+
+// fileStamp is what the watch mode compares to find the changed files. A dir changes when its entries change, not when
+// a file is rewritten in it.
+type fileStamp struct {
+	exists  bool
+	isDir   bool
+	size    int64
+	modTime time.Time
+	entries string
+}
+
+// stampFiles returns the current stamps of the files, a missing file has a stamp too so creating it is a change.
+func stampFiles(files []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			stamps[file] = fileStamp{}
+			continue
+		}
+		if info.IsDir() {
+			entries, _ := os.ReadDir(file)
+			names := make([]string, len(entries))
+			for i, entry := range entries {
+				names[i] = entry.Name()
+			}
+			stamps[file] = fileStamp{exists: true, isDir: true, entries: strings.Join(names, "/")}
+			continue
+		}
+		stamps[file] = fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+	}
+	return stamps
+}
+
+// changedFiles returns the files whose stamp changed since the stamps were taken, in name order.
+func changedFiles(stamps map[string]fileStamp) []string {
+	changed := make([]string, 0)
+	for file, stamp := range stamps {
+		current := stampFiles([]string{file})[file]
+		if current.exists != stamp.exists || current.isDir != stamp.isDir || current.size != stamp.size ||
+			!current.modTime.Equal(stamp.modTime) || current.entries != stamp.entries {
+			changed = append(changed, file)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
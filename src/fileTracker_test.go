@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileTracker(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tracker := NewFileTracker()
+	if _, err := fs.ReadFile(tracker.FS(dir), "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile(tracker.FS(dir), "missing.txt"); err == nil {
+		t.Fatal("ReadFile() of a missing file succeeded")
+	}
+	want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "missing.txt")}
+	if got := tracker.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+	var none *FileTracker
+	if _, err := fs.ReadFile(none.FS(dir), "a.txt"); err != nil || none.Files() != nil {
+		t.Errorf("nil tracker: error = %v, files = %v", err, none.Files())
+	}
+}
+
+// A file changed after it was opened, e.g. while the feature sets are rendered, is changed since its stamp.
+func TestFileTracker_Stamps(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(a, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tracker := NewFileTracker()
+	if _, err := fs.ReadFile(tracker.FS(dir), "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(a, []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := changedFiles(tracker.Stamps()), []string{a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changedFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b, c := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")
+	for _, file := range []string{a, b} {
+		if err := os.WriteFile(file, []byte("1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stamps := stampFiles([]string{a, b, c, dir})
+	if got := changedFiles(stamps); len(got) != 0 {
+		t.Errorf("changedFiles() = %v, want none", got)
+	}
+	// rewriting a file in a dir doesn't change the dir, adding one does
+	if err := os.WriteFile(a, []byte("22"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := changedFiles(stamps), []string{a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changedFiles() = %v, want %v", got, want)
+	}
+	if err := os.WriteFile(c, []byte("3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := changedFiles(stamps), []string{dir, a, c}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changedFiles() = %v, want %v", got, want)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"gopkg.in/yaml.v3"
 	"io"
 	"io/fs"
-	"log"
+	"log/slog"
+	"path"
+	"slices"
 	"strings"
 )
 
@@ -21,8 +23,10 @@ type InputSource interface {
 type CsvFileInputSource struct {
 	// Path is the path to the CSV file.
 	path string
-	// Headers is the list of headers in the CSV file.
+	// Headers is the list of headers in the CSV file. When empty, the first line of the file is used as headers.
 	headers []string
+	// Optional, collects the warnings
+	warnings *Warnings
 }
 
 // Provide reads the CSV file and returns the data as []map[string]interface{}.
@@ -36,6 +40,18 @@ func (config *CsvFileInputSource) Provide(filesystem fs.FS) (d interface{}, err
 	}()
 	records := make([]map[string]interface{}, 0)
 	reader := csv.NewReader(f)
+	headers := config.headers
+	if len(headers) == 0 {
+		headers, err = reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading csv headers from %s: %v", config.path, err)
+		}
+		// the field count is decided by the header line
+		reader.FieldsPerRecord = -1
+	}
 	for {
 		line, err := reader.Read()
 		if err == io.EOF {
@@ -45,11 +61,11 @@ func (config *CsvFileInputSource) Provide(filesystem fs.FS) (d interface{}, err
 			myErr := fmt.Errorf(fmt.Sprintf("Error reading csv line from %s: %v", config.path, err))
 			return nil, myErr
 		}
-		if len(line) != len(config.headers) {
-			log.Printf("Record length %d does not match header length %d", len(line), len(config.headers))
+		if len(line) != len(headers) {
+			config.warnings.Warnf(WarnRecordLength, "Record length %d does not match header length %d", len(line), len(headers))
 		}
 		m := make(map[string]interface{})
-		for i, header := range config.headers {
+		for i, header := range headers {
 			if i < len(line) {
 				m[header] = line[i]
 			} else {
@@ -86,8 +102,27 @@ type PlainTextFileInputSource struct {
 	trim          bool
 }
 
+// textLine is a line of a text file with its line number
+type textLine struct {
+	number int
+	text   string
+}
+
 // Provide reads the plain text file and returns the data as []string.
 func (receiver PlainTextFileInputSource) Provide(filesystem fs.FS) (d interface{}, err error) {
+	lines, err := receiver.lines(filesystem)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]string, len(lines))
+	for i, line := range lines {
+		records[i] = line.text
+	}
+	return records, nil
+}
+
+// lines reads the plain text file like Provide, keeping the line numbers.
+func (receiver PlainTextFileInputSource) lines(filesystem fs.FS) ([]textLine, error) {
 	f, err := filesystem.Open(receiver.path)
 	if err != nil {
 		panic(fmt.Sprintf("Error reading %s: %v", receiver.path, err))
@@ -95,8 +130,8 @@ func (receiver PlainTextFileInputSource) Provide(filesystem fs.FS) (d interface{
 	defer f.Close()
 
 	sc := bufio.NewScanner(f)
-	records := make([]string, 0)
-	for sc.Scan() {
+	records := make([]textLine, 0)
+	for number := 1; sc.Scan(); number++ {
 		line := sc.Text()
 		if receiver.ignoreComment {
 			idx := strings.Index(line, "#")
@@ -108,35 +143,220 @@ func (receiver PlainTextFileInputSource) Provide(filesystem fs.FS) (d interface{
 			line = strings.TrimSpace(line)
 		}
 		if len(line) > 0 {
-			records = append(records, line)
+			records = append(records, textLine{number: number, text: line})
 		}
 	}
-	return records, nil
+	return records, sc.Err()
 }
 
 type YamlInputSource struct {
 	path    string
 	flatten int
+	// Optional, the data read is logged at debug level
+	logger *slog.Logger
 }
 
 // Provide reads the YAML file and returns the data as map[interface{}]interface{}.
 func (config YamlInputSource) Provide(filesystem fs.FS) (data interface{}, err error) {
+	node, err := config.ProvideNode(filesystem)
+	if err != nil {
+		return nil, err
+	}
+	return config.DecodeNode(node)
+}
+
+// DecodeNode decodes the node read by ProvideNode to the data Provide returns.
+func (config YamlInputSource) DecodeNode(node *yaml.Node) (interface{}, error) {
+	m := make(map[interface{}]interface{})
+	if err := node.Decode(&m); err != nil {
+		return nil, err
+	}
+	loggerOrDefault(config.logger).Debug("YAML file read", "path", config.path, "data", dump(m))
+	return m, nil
+}
+
+// ProvideNode reads the YAML file as a node, which keeps the line numbers for validation.
+func (config YamlInputSource) ProvideNode(filesystem fs.FS) (*yaml.Node, error) {
 	f, err := filesystem.Open(config.path)
 	if err != nil {
 		panic(fmt.Sprintf("Error reading %s: %v", config.path, err))
 	}
 	defer f.Close()
 
-	m := make(map[interface{}]interface{})
 	bytes, err := io.ReadAll(f)
 	if err != nil {
 		return nil, err
 	}
-	err = yaml.Unmarshal(bytes, &m)
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(bytes, node); err != nil {
+		return nil, err
+	}
+	if node.Kind == 0 {
+		// an empty file
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	return node, nil
+}
+
+// FeatureListInputSource reads a feature list, a plain text file with one feature per line. Comments start with # and
+// the lines may use these directives:
+//
+//	!Feature               remove the feature added by the lines before
+//	@include base.txt      add the features of another feature list, the path is relative to the current file
+//	@group name            add the features of a group
+type FeatureListInputSource struct {
+	path string
+	// The entries of each group, they may use the directives too
+	groups map[string][]string
+	// Optional, collects the warnings
+	warnings *Warnings
+	// Optional, records where the features were added and removed
+	report *FeatureListReport
+}
+
+// FeatureListReport records the lines of the feature lists and groups adding or removing a feature, in order.
+type FeatureListReport struct {
+	Origins []FeatureOrigin
+}
+
+// FeatureOrigin is a line adding or removing a feature.
+type FeatureOrigin struct {
+	Name string
+	// The feature list, or "@group name" for an entry of a group
+	Source string
+	// The line of the feature list, or the number of the entry of the group
+	Line    int
+	Removed bool
+}
+
+func (origin FeatureOrigin) String() string {
+	if strings.HasPrefix(origin.Source, "@") {
+		return fmt.Sprintf("%s, entry %d", origin.Source, origin.Line)
+	}
+	return fmt.Sprintf("%s:%d", origin.Source, origin.Line)
+}
+
+// Provide reads the feature list and returns the data as []string.
+func (config FeatureListInputSource) Provide(filesystem fs.FS) (d interface{}, err error) {
+	records := make([]string, 0)
+	err = config.include(filesystem, config.path, &records, []string{})
 	if err != nil {
 		return nil, err
 	}
+	return records, nil
+}
 
-	fmt.Printf("%+v\n", m)
-	return m, nil
+// include reads the feature list at file. The stack holds the files and groups being processed to detect cycles.
+func (config FeatureListInputSource) include(filesystem fs.FS, file string, records *[]string, stack []string) error {
+	if slices.Contains(stack, file) {
+		return fmt.Errorf("Include cycle %s -> %s", strings.Join(stack, " -> "), file)
+	}
+	if _, err := fs.Stat(filesystem, file); err != nil {
+		return fmt.Errorf("Error reading %s: %v", file, err)
+	}
+	step := PlainTextFileInputSource{
+		path:          file,
+		ignoreComment: true,
+		trim:          true,
+	}
+	lines, err := step.lines(filesystem)
+	if err != nil {
+		return err
+	}
+	return config.process(filesystem, path.Dir(file), lines, records, append(stack, file))
+}
+
+// process applies the lines of a feature list or group to the records.
+func (config FeatureListInputSource) process(filesystem fs.FS, dir string, lines []textLine, records *[]string, stack []string) error {
+	source := stack[len(stack)-1]
+	for _, numbered := range lines {
+		line := numbered.text
+		switch {
+		case strings.HasPrefix(line, "!"):
+			name := strings.TrimSpace(line[1:])
+			config.record(FeatureOrigin{Name: name, Source: source, Line: numbered.number, Removed: true})
+			count := len(*records)
+			*records = slices.DeleteFunc(*records, func(s string) bool { return s == name })
+			if count == len(*records) {
+				config.warnings.Warnf(WarnRemovedNotListed, "Feature '%s' removed in %s is not in the list", name, source)
+			}
+		case strings.HasPrefix(line, "@include "):
+			file := path.Join(dir, strings.TrimSpace(strings.TrimPrefix(line, "@include ")))
+			if err := config.include(filesystem, file, records, stack); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "@group "):
+			name := strings.TrimSpace(strings.TrimPrefix(line, "@group "))
+			entries, ok := config.groups[name]
+			if !ok {
+				return fmt.Errorf("Unknown group '%s' in %s", name, source)
+			}
+			group := "@group " + name
+			if slices.Contains(stack, group) {
+				return fmt.Errorf("Group cycle %s -> %s", strings.Join(stack, " -> "), group)
+			}
+			groupLines := make([]textLine, len(entries))
+			for i, entry := range entries {
+				groupLines[i] = textLine{number: i + 1, text: entry}
+			}
+			if err := config.process(filesystem, dir, groupLines, records, append(stack, group)); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "@"):
+			return fmt.Errorf("Unknown directive '%s' in %s", line, source)
+		default:
+			config.record(FeatureOrigin{Name: line, Source: source, Line: numbered.number})
+			*records = append(*records, line)
+		}
+	}
+	return nil
+}
+
+func (config FeatureListInputSource) record(origin FeatureOrigin) {
+	if config.report != nil {
+		config.report.Origins = append(config.report.Origins, origin)
+	}
+}
+
+// NodeInputSource is an InputSource that can provide its data as a YAML node, with the line numbers of the source.
+type NodeInputSource interface {
+	InputSource
+	ProvideNode(filesystem fs.FS) (*yaml.Node, error)
+	DecodeNode(node *yaml.Node) (interface{}, error)
+}
+
+// ValidatingInputSource validates the data of another source against a schema. The errors of a NodeInputSource have
+// the line numbers of the source, other sources are validated by their data.
+type ValidatingInputSource struct {
+	source InputSource
+	schema *Schema
+	// The name of the source in the errors
+	name string
+}
+
+// Provide returns the data of the source, or SchemaErrors when it doesn't match the schema. The node of a
+// NodeInputSource is read once, it's validated and then decoded.
+func (config ValidatingInputSource) Provide(filesystem fs.FS) (d interface{}, err error) {
+	var problems SchemaErrors
+	if source, ok := config.source.(NodeInputSource); ok {
+		node, err := source.ProvideNode(filesystem)
+		if err != nil {
+			return nil, err
+		}
+		if problems = config.schema.Validate(config.name, node); len(problems) > 0 {
+			return nil, problems
+		}
+		return source.DecodeNode(node)
+	}
+	d, err = config.source.Provide(filesystem)
+	if err != nil {
+		return nil, err
+	}
+	if problems, err = config.schema.ValidateValue(config.name, d); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return d, nil
 }
@@ -59,6 +59,24 @@ func TestCsvFileSource_Provide(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Headers from the first line",
+			fields: fields{
+				path: "test.csv",
+			},
+			args: args{
+				filesystem: fstest.MapFS{
+					"test.csv": {
+						Data: []byte("header1,header2\nvalue1,value2\nvalue3\n"),
+					},
+				},
+			},
+			wantD: []map[string]interface{}{
+				{"header1": "value1", "header2": "value2"},
+				{"header1": "value3", "header2": ""},
+			},
+			wantErr: false,
+		},
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
@@ -209,3 +227,205 @@ func TestPlainTextFileInputSource_Provide(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureListInputSource_Provide(t *testing.T) {
+	type fields struct {
+		path   string
+		groups map[string][]string
+	}
+	type args struct {
+		filesystem fs.FS
+	}
+	filesystem := fstest.MapFS{
+		"base.txt": {
+			Data: []byte("Foo\nBar # comment\nBaz\n"),
+		},
+		"env/staging.txt": {
+			Data: []byte("@include ../base.txt\n!Bar\nQux\n@group web\n"),
+		},
+		"cycle.txt": {
+			Data: []byte("Foo\n@include cycle.txt\n"),
+		},
+		"unknown-group.txt": {
+			Data: []byte("@group unknown\n"),
+		},
+		"unknown-directive.txt": {
+			Data: []byte("@import base.txt\n"),
+		},
+		"group-cycle.txt": {
+			Data: []byte("@group loop\n"),
+		},
+	}
+	groups := map[string][]string{
+		"web":  {"Web1", "!Foo", "@group api"},
+		"api":  {"Api1"},
+		"loop": {"@group loop"},
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantD   interface{}
+		wantErr bool
+	}{
+		{
+			name: "plain list",
+			fields: fields{
+				path: "base.txt",
+			},
+			args: args{
+				filesystem: filesystem,
+			},
+			wantD: []string{"Foo", "Bar", "Baz"},
+		},
+		{
+			name: "include, negation and groups",
+			fields: fields{
+				path:   "env/staging.txt",
+				groups: groups,
+			},
+			args: args{
+				filesystem: filesystem,
+			},
+			wantD: []string{"Baz", "Qux", "Web1", "Api1"},
+		},
+		{
+			name: "include cycle",
+			fields: fields{
+				path: "cycle.txt",
+			},
+			args: args{
+				filesystem: filesystem,
+			},
+			wantErr: true,
+		},
+		{
+			name: "group cycle",
+			fields: fields{
+				path:   "group-cycle.txt",
+				groups: groups,
+			},
+			args: args{
+				filesystem: filesystem,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown group",
+			fields: fields{
+				path:   "unknown-group.txt",
+				groups: groups,
+			},
+			args: args{
+				filesystem: filesystem,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown directive",
+			fields: fields{
+				path: "unknown-directive.txt",
+			},
+			args: args{
+				filesystem: filesystem,
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing file",
+			fields: fields{
+				path: "missing.txt",
+			},
+			args: args{
+				filesystem: filesystem,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := FeatureListInputSource{
+				path:   tt.fields.path,
+				groups: tt.fields.groups,
+			}
+			gotD, err := config.Provide(tt.args.filesystem)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Provide() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotD, tt.wantD) {
+				t.Errorf("Provide() gotD = %v, want %v", gotD, tt.wantD)
+			}
+		})
+	}
+}
+
+func TestValidatingInputSource_Provide(t *testing.T) {
+	schema, err := loadSchema([]byte(`{"type": "object", "additionalProperties": {"type": "object", "required": ["in"]}}`))
+	if err != nil {
+		t.Fatalf("loadSchema() error = %v", err)
+	}
+	filesystem := fstest.MapFS{
+		"valid.yaml":   {Data: []byte("Foo:\n  in: A\n")},
+		"invalid.yaml": {Data: []byte("Foo:\n  in: A\nBar:\n  on: B\n")},
+		"owners.csv":   {Data: []byte("Name,Owner\nFoo,team\n")},
+	}
+	tests := []struct {
+		name    string
+		file    string
+		source  InputSource
+		want    interface{}
+		wantErr string
+	}{
+		{name: "valid", file: "valid.yaml", source: YamlInputSource{path: "valid.yaml"}, want: map[interface{}]interface{}{"Foo": map[string]interface{}{"in": "A"}}},
+		{name: "invalid, with line", file: "invalid.yaml", source: YamlInputSource{path: "invalid.yaml"}, wantErr: "invalid.yaml:4:3: Bar: missing required property 'in'"},
+		{name: "other source, without line", file: "owners.csv", source: &CsvFileInputSource{path: "owners.csv"}, wantErr: "owners.csv: .: must be object, not array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := ValidatingInputSource{source: tt.source, schema: schema, name: tt.file}
+			got, err := source.Provide(filesystem)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Provide() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Provide() got = %v, error = %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeatureListInputSource_Report(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"base.txt":        {Data: []byte("# base\nFoo\n\nBar # comment\n")},
+		"env/staging.txt": {Data: []byte("@include ../base.txt\n!Bar\n@group web\n")},
+	}
+	report := FeatureListReport{}
+	config := FeatureListInputSource{
+		path:   "env/staging.txt",
+		groups: map[string][]string{"web": {"Web1", "!Foo"}},
+		report: &report,
+	}
+	if _, err := config.Provide(filesystem); err != nil {
+		t.Fatalf("Provide() error = %v", err)
+	}
+	want := []FeatureOrigin{
+		{Name: "Foo", Source: "base.txt", Line: 2},
+		{Name: "Bar", Source: "base.txt", Line: 4},
+		{Name: "Bar", Source: "env/staging.txt", Line: 2, Removed: true},
+		{Name: "Web1", Source: "@group web", Line: 1},
+		{Name: "Foo", Source: "@group web", Line: 2, Removed: true},
+	}
+	if !reflect.DeepEqual(report.Origins, want) {
+		t.Errorf("Origins = %v, want %v", report.Origins, want)
+	}
+	if got := want[2].String(); got != "env/staging.txt:2" {
+		t.Errorf("String() = %s", got)
+	}
+	if got := want[3].String(); got != "@group web, entry 1" {
+		t.Errorf("String() = %s", got)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+type JoinType int
+
+const (
+	// InnerJoin keeps the left elements that match at least one right element, once per match.
+	InnerJoin JoinType = iota
+	// LeftJoin keeps every left element, merged with the matching right elements if any.
+	LeftJoin
+	// AntiJoin keeps only the left elements that match no right element, unmodified.
+	AntiJoin
+)
+
+// CollisionPolicy decides what happens when both sides of a join carry the same field with different values.
+type CollisionPolicy int
+
+const (
+	// KeepLeft keeps the value of the left element.
+	KeepLeft CollisionPolicy = iota
+	// KeepRight overwrites the value with the one of the right element.
+	KeepRight
+	// PrefixRight stores the value of the right element as <prefix><field>.
+	PrefixRight
+	// FailOnCollision aborts the join with an error.
+	FailOnCollision
+)
+
+// JoinReport collects the elements of a join that did not find a partner.
+type JoinReport struct {
+	// UnmatchedLeft are the left elements that matched no right element.
+	UnmatchedLeft []interface{}
+	// UnmatchedRight are the right elements that matched no left element.
+	UnmatchedRight []interface{}
+}
+
+// JoinTransformer combines the input list (left) with another list (right) by the keys the key mappers return.
+// Both lists must contain maps, the joined elements are map[string]interface{}.
+type JoinTransformer struct {
+	right     interface{}
+	leftKey   Mapper
+	rightKey  Mapper
+	joinType  JoinType
+	collision CollisionPolicy
+	// The prefix of the right fields when collision is PrefixRight
+	prefix string
+	// Optional, receives the unmatched elements
+	report *JoinReport
+	// Optional, collects the warnings
+	warnings *Warnings
+}
+
+// Transform joins the input list with the right list.
+func (config JoinTransformer) Transform(input interface{}) (interface{}, error) {
+	if input == nil {
+		return nil, errors.New("JoinTransformer: Input is nil")
+	}
+	if reflect.TypeOf(input).Kind() != reflect.Slice {
+		return nil, errors.New("JoinTransformer: Input is not a list")
+	}
+	if config.right == nil || reflect.TypeOf(config.right).Kind() != reflect.Slice {
+		return nil, errors.New("JoinTransformer: Right side is not a list")
+	}
+	// index the right side by key, remember which right elements were used
+	rightV := reflect.ValueOf(config.right)
+	rightByKey := make(map[string][]int)
+	for i := 0; i < rightV.Len(); i++ {
+		key := config.rightKey(rightV.Index(i).Interface())
+		if key == nil {
+			continue
+		}
+		k := fmt.Sprint(key)
+		rightByKey[k] = append(rightByKey[k], i)
+	}
+	rightUsed := make([]bool, rightV.Len())
+
+	report := JoinReport{}
+	listV := reflect.ValueOf(input)
+	records := make([]interface{}, 0)
+	for i := 0; i < listV.Len(); i++ {
+		el := listV.Index(i).Interface()
+		var matches []int
+		if key := config.leftKey(el); key != nil {
+			matches = rightByKey[fmt.Sprint(key)]
+		}
+		if len(matches) == 0 {
+			report.UnmatchedLeft = append(report.UnmatchedLeft, el)
+			switch config.joinType {
+			case LeftJoin:
+				merged, err := config.merge(el, nil)
+				if err != nil {
+					return nil, err
+				}
+				records = append(records, merged)
+			case AntiJoin:
+				records = append(records, el)
+			}
+			continue
+		}
+		for _, j := range matches {
+			rightUsed[j] = true
+		}
+		if config.joinType == AntiJoin {
+			continue
+		}
+		for _, j := range matches {
+			merged, err := config.merge(el, rightV.Index(j).Interface())
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, merged)
+		}
+	}
+	for j, used := range rightUsed {
+		if !used {
+			report.UnmatchedRight = append(report.UnmatchedRight, rightV.Index(j).Interface())
+		}
+	}
+
+	// report unmatched elements
+	for _, el := range report.UnmatchedLeft {
+		config.warnings.Warnf(WarnUnmatchedLeft, "Left element with key '%v' not matched in join transformer", config.leftKey(el))
+	}
+	for _, el := range report.UnmatchedRight {
+		config.warnings.Warnf(WarnUnmatchedRight, "Right element with key '%v' not matched in join transformer", config.rightKey(el))
+	}
+	if config.report != nil {
+		*config.report = report
+	}
+	return records, nil
+}
+
+// merge copies the fields of left and right into a new map, resolving collisions by the collision policy.
+func (config JoinTransformer) merge(left interface{}, right interface{}) (map[string]interface{}, error) {
+	m, err := copyToStringMap(left)
+	if err != nil {
+		return nil, fmt.Errorf("JoinTransformer: %w", err)
+	}
+	if right == nil {
+		return m, nil
+	}
+	r, err := copyToStringMap(right)
+	if err != nil {
+		return nil, fmt.Errorf("JoinTransformer: %w", err)
+	}
+	for k, v := range r {
+		existing, ok := m[k]
+		if !ok {
+			m[k] = v
+			continue
+		}
+		if reflect.DeepEqual(existing, v) {
+			continue
+		}
+		switch config.collision {
+		case KeepRight:
+			m[k] = v
+		case PrefixRight:
+			m[config.prefix+k] = v
+		case FailOnCollision:
+			return nil, fmt.Errorf("JoinTransformer: Field '%s' has different values '%v' and '%v'", k, existing, v)
+		}
+	}
+	return m, nil
+}
+
+// copyToStringMap copies any map into a new map[string]interface{}, keys are converted by fmt.Sprint.
+func copyToStringMap(input interface{}) (map[string]interface{}, error) {
+	if input == nil || reflect.TypeOf(input).Kind() != reflect.Map {
+		return nil, fmt.Errorf("element '%v' is not a map", input)
+	}
+	m := make(map[string]interface{})
+	iter := reflect.ValueOf(input).MapRange()
+	for iter.Next() {
+		m[fmt.Sprint(iter.Key().Interface())] = iter.Value().Interface()
+	}
+	return m, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinTransformer_Transform(t *testing.T) {
+	type fields struct {
+		right     interface{}
+		joinType  JoinType
+		collision CollisionPolicy
+		prefix    string
+	}
+	type args struct {
+		input interface{}
+	}
+	features := []interface{}{
+		map[string]interface{}{"Name": "Foo", "priority": "A01"},
+		map[string]interface{}{"Name": "Bar", "priority": "A02"},
+	}
+	owners := []map[string]interface{}{
+		{"Name": "Foo", "owner": "team-a", "priority": "Z99"},
+		{"Name": "Qux", "owner": "team-c"},
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		want       interface{}
+		wantReport JoinReport
+		wantErr    bool
+	}{
+		{
+			name: "inner join",
+			fields: fields{
+				right:    owners,
+				joinType: InnerJoin,
+			},
+			args: args{
+				input: features,
+			},
+			want: []interface{}{
+				map[string]interface{}{"Name": "Foo", "priority": "A01", "owner": "team-a"},
+			},
+			wantReport: JoinReport{
+				UnmatchedLeft:  []interface{}{features[1]},
+				UnmatchedRight: []interface{}{owners[1]},
+			},
+		},
+		{
+			name: "left join, keep right on collision",
+			fields: fields{
+				right:     owners,
+				joinType:  LeftJoin,
+				collision: KeepRight,
+			},
+			args: args{
+				input: features,
+			},
+			want: []interface{}{
+				map[string]interface{}{"Name": "Foo", "priority": "Z99", "owner": "team-a"},
+				map[string]interface{}{"Name": "Bar", "priority": "A02"},
+			},
+			wantReport: JoinReport{
+				UnmatchedLeft:  []interface{}{features[1]},
+				UnmatchedRight: []interface{}{owners[1]},
+			},
+		},
+		{
+			name: "left join, prefix right on collision",
+			fields: fields{
+				right:     owners,
+				joinType:  LeftJoin,
+				collision: PrefixRight,
+				prefix:    "csv-",
+			},
+			args: args{
+				input: features[:1],
+			},
+			want: []interface{}{
+				map[string]interface{}{"Name": "Foo", "priority": "A01", "csv-priority": "Z99", "owner": "team-a"},
+			},
+			wantReport: JoinReport{
+				UnmatchedRight: []interface{}{owners[1]},
+			},
+		},
+		{
+			name: "anti join",
+			fields: fields{
+				right:    owners,
+				joinType: AntiJoin,
+			},
+			args: args{
+				input: features,
+			},
+			want: []interface{}{
+				features[1],
+			},
+			wantReport: JoinReport{
+				UnmatchedLeft:  []interface{}{features[1]},
+				UnmatchedRight: []interface{}{owners[1]},
+			},
+		},
+		{
+			name: "fail on collision",
+			fields: fields{
+				right:     owners,
+				joinType:  InnerJoin,
+				collision: FailOnCollision,
+			},
+			args: args{
+				input: features,
+			},
+			wantErr: true,
+		},
+		{
+			name: "right is not a list",
+			fields: fields{
+				right: "owners",
+			},
+			args: args{
+				input: features,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := JoinReport{}
+			config := JoinTransformer{
+				right:     tt.fields.right,
+				leftKey:   StringMapMapper("Name"),
+				rightKey:  StringMapMapper("Name"),
+				joinType:  tt.fields.joinType,
+				collision: tt.fields.collision,
+				prefix:    tt.fields.prefix,
+				report:    &report,
+			}
+			got, err := config.Transform(tt.args.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(report, tt.wantReport) {
+				t.Errorf("Transform() report = %v, want %v", report, tt.wantReport)
+			}
+		})
+	}
+}
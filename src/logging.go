@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// newLogger returns the logger of the --log-level and --log-format arguments, writing to w.
+func newLogger(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level '%s', use debug, info, warn or error", level)
+	}
+	options := &slog.HandlerOptions{Level: logLevel}
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unknown log format '%s', use text or json", format)
+}
+
+// loggerOrDefault returns the injected logger, or the default one if none was.
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// contextLogger returns the logger of the run.
+func contextLogger(context map[string]interface{}) *slog.Logger {
+	logger, _ := context["logger"].(*slog.Logger)
+	return loggerOrDefault(logger)
+}
+
+// dump formats the value for a debug log, the values of the pipeline are not all JSON. The value is only formatted when
+// the log is written, not when debug is disabled.
+func dump(value interface{}) slog.LogValuer {
+	return dumpValue{value}
+}
+
+type dumpValue struct {
+	value interface{}
+}
+
+func (d dumpValue) LogValue() slog.Value {
+	return slog.StringValue(fmt.Sprintf("%+v", d.value))
+}
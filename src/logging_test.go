@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		want    string
+		wantErr string
+	}{
+		{name: "text at info", level: "info", format: "text", want: "level=INFO msg=info\nlevel=WARN msg=warn type=test\n"},
+		{name: "json at debug", level: "debug", format: "json", want: `{"level":"DEBUG","msg":"debug"}` + "\n" +
+			`{"level":"INFO","msg":"info"}` + "\n" + `{"level":"WARN","msg":"warn","type":"test"}` + "\n"},
+		{name: "warn, upper case", level: "WARN", format: "TEXT", want: "level=WARN msg=warn type=test\n"},
+		{name: "unknown level", level: "verbose", format: "text", wantErr: "unknown log level 'verbose', use debug, info, warn or error"},
+		{name: "unknown format", level: "info", format: "xml", wantErr: "unknown log format 'xml', use text or json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			logger, err := newLogger(&w, tt.level, tt.format)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("newLogger() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newLogger() error = %v", err)
+			}
+			logger = slog.New(withoutTimeHandler{logger.Handler()})
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn", "type", "test")
+			if w.String() != tt.want {
+				t.Errorf("logs = %q, want %q", w.String(), tt.want)
+			}
+		})
+	}
+}
+
+// withoutTimeHandler drops the time of the records so the logs can be compared, the handlers omit a zero time
+type withoutTimeHandler struct {
+	slog.Handler
+}
+
+func (handler withoutTimeHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.Time = time.Time{}
+	return handler.Handler.Handle(ctx, record)
+}
+
+// countingValue counts how many times it's formatted
+type countingValue struct {
+	count *int
+}
+
+func (v countingValue) String() string {
+	*v.count++
+	return "counted"
+}
+
+func TestDump_Lazy(t *testing.T) {
+	count := 0
+	var w bytes.Buffer
+	logger, err := newLogger(&w, "info", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("skipped", "data", dump(countingValue{&count}))
+	if count != 0 || w.Len() != 0 {
+		t.Errorf("dump formatted at info level: %d times, %q", count, w.String())
+	}
+	logger, _ = newLogger(&w, "debug", "text")
+	logger.Debug("logged", "data", dump(countingValue{&count}))
+	if count != 1 || !bytes.Contains(w.Bytes(), []byte("data=counted")) {
+		t.Errorf("dump at debug level: %d times, %q", count, w.String())
+	}
+}
@@ -1,163 +1,439 @@
 package main
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"github.com/alexflint/go-arg"
-	"html/template"
-	"io"
+	"io/fs"
+	"log/slog"
 	"os"
-	"path"
+	"slices"
+	"strings"
+	"text/template"
 )
 
+// The key of config.yaml holding the feature groups instead of a feature
+const featureGroupsKey = "_groups"
+
+// Args are the arguments shared by all commands
 type Args struct {
-	ConfigDir          string   `arg:"positional"`
-	FeatureFile        string   `arg:"--feature-file,required"`
-	FeatureMappingFile string   `arg:"--feature-mapping-file,required"`
+	ConfigDir          string   `arg:"--config-dir" default:"." help:"dir of the config file, the feature lists and the property files"`
+	FeatureFile        []string `arg:"--feature-file,separate" help:"feature list, repeat the flag to combine several lists, required"`
+	ExcludeFeatureFile []string `arg:"--exclude-feature-file,separate" help:"feature list of the features to leave out"`
+	DuplicatePolicy    string   `arg:"--duplicate-policy" default:"first" help:"how to handle duplicated features: first, last or error"`
+	FeatureMappingFile string   `arg:"--feature-mapping-file" help:"required"`
+	ChainMapping       bool     `arg:"--chain-feature-mapping" help:"apply the feature mapping until the names no longer change"`
 	ConfigFile         string   `arg:"--config-file" default:"config.yaml"`
-	FeatureSet         []string `arg:"--feature-set"`
-	PropertyFiles      []string `arg:"--property-file"`
-	TemplateDir        string   `arg:"--template-dir,required"`
+	ConfigSchema       []string `arg:"--config-schema,separate" help:"JSON Schema merged over the bundled schema of the config file, repeatable"`
+	NoConfigValidation bool     `arg:"--no-config-validation" help:"do not validate the config file against the schema"`
+	FeatureSet         []string `arg:"--feature-set,separate"`
+	GroupBy            string   `arg:"--group-by" default:"feature-set" help:"dot separated key path of the features naming their feature sets, e.g. x-owner.team"`
+	AllFeatureSets     bool     `arg:"--all-feature-sets" help:"render every feature set found in the features"`
+	PropertyFiles      []string `arg:"--property-file,separate"`
+	TemplateDir        string   `arg:"--template-dir" help:"dir of the templates, required by render and validate"`
+	TemplateLibDir     string   `arg:"--template-lib-dir" help:"dir of more _*.tmpl partials shared by the templates"`
+	OutputDir          string   `arg:"--output-dir" help:"dir to write the rendered files to, printed if omitted"`
+	EnrichFile         string   `arg:"--enrich-file" help:"CSV file whose rows are merged into the features"`
+	EnrichHeaders      []string `arg:"--enrich-headers,separate" help:"header of the enrich file, repeatable, read from its first line if omitted"`
+	ValidateOutput     bool     `arg:"--validate-output" help:"validate the output by its extension, unless the front-matter sets validate"`
+	FormatOutput       bool     `arg:"--format-output" help:"re-format the validated output canonically"`
+	TypedFeatures      bool     `arg:"--typed-features" help:"decode the features into the typed model, unknown fields are errors"`
+	EnrichKey          string   `arg:"--enrich-key" default:"Name" help:"column of the enrich file matching the feature name"`
+	CacheDir           string   `arg:"--cache-dir" default:".examplar-cache" help:"dir of the render cache, used with --output-dir"`
+	NoCache            bool     `arg:"--no-cache" help:"render every feature set, the cache is neither read nor written"`
+	Jobs               int      `arg:"--jobs" help:"number of feature sets rendered concurrently [default: number of CPUs]"`
+	Watch              bool     `arg:"--watch" help:"render again the feature sets whose inputs or templates change, until interrupted"`
+	Profile            string   `arg:"--profile" help:"profile of the examplar.yaml project file adding property files and feature sets"`
+	Report             string   `arg:"--report" help:"JSON file to write the report of the run to: steps, warnings, files and property usage"`
+	LogLevel           string   `arg:"--log-level" default:"info" help:"level of the logs written to stderr: debug, info, warn or error"`
+	LogFormat          string   `arg:"--log-format" default:"text" help:"format of the logs: text or json"`
+	DumpAfter          string   `arg:"--dump-after" help:"dump the context after the pipeline step: config, features, mapping, transform, properties, templates or group"`
+	DumpContext        bool     `arg:"--dump-context" help:"dump the context after the last pipeline step"`
+	DumpKeys           []string `arg:"--dump-key,separate" help:"key of the context to dump, repeatable, all the data if omitted"`
+	DumpFormat         string   `arg:"--dump-format" default:"yaml" help:"format of the dump: yaml or json"`
+	DumpFile           string   `arg:"--dump-file" help:"file to dump the context to, stdout if omitted"`
+	DryRun             bool     `arg:"--dry-run" help:"run the pipeline steps and stop before rendering"`
+	WarningPolicy      []string `arg:"--warning-policy,separate" help:"type=ignore|warn|error, what a warning type does, repeatable, all=... for the types without one"`
+}
+
+// Commands are the subcommands, render is the default
+type Commands struct {
+	Render   *RenderCommand   `arg:"subcommand:render" help:"render the templates of the feature sets (default)"`
+	Validate *ValidateCommand `arg:"subcommand:validate" help:"run all the steps and validate the outputs without writing them"`
+	List     *ListCommand     `arg:"subcommand:list" help:"print the resolved features, feature sets or properties"`
+	Explain  *ExplainCommand  `arg:"subcommand:explain" help:"show how a feature got its final shape"`
+	Graph    *GraphCommand    `arg:"subcommand:graph" help:"print the dependencies of the features"`
+}
+
+// cliArgs are the arguments of the command line, the struct embedding keeps the declared types so they can be referred
+// to later
+type cliArgs struct {
+	Args
+	Commands
 }
 
 func main() {
-	// use struct embedding to create a anonymous struct while still using a declared interface
-	// so it can be referred to later
-	var args struct {
-		Args
-	}
-	arg.MustParse(&args)
-	fmt.Printf("args: %v\n", args)
-
-	context := make(map[string]interface{})
-	context["args"] = args.Args
-	// 1. Read features from a plain text file
-	context["raw-features"] = readFeatures(context)
-	// 2. Read feature mapping from a properties file
-	context["feature-mapping"] = readFeatureMapping(context)
-	// 3. Convert feature names using the mapping
-	context["features"] = convertFeatureNames(context)
-	// 4. Read config.yaml from a YAML file
-	context["config"] = readConfigFile(context)
-	// 5. Expand according to configuration in config.yaml
-	context["features"] = expandFeatureByConfig(context)
-	// 6. Read properties from property files
-	properties := readProperties(context)
-	// 7. For each feature set, filter the features
-	for _, featureSet := range args.FeatureSet {
-		// The per-feature set results will be stored at "feature-<featureSet>"
-		contextVarName := fmt.Sprintf("feature-%s", featureSet)
-		// a. Filter the feature based on config#featureSet and put it to per-feature set context variable
-		context[contextVarName] = filterFeatureByFeatureSet(context, featureSet)
-		// b. Sort the features based on config#priority
-		context[contextVarName] = sortFeatureSetByPriority(context, contextVarName)
-		fmt.Printf("Context: %+v\n", context)
-		// c. Prepare the context for rendering
-		tc := make(map[string]interface{})
-		tc["features"] = context[contextVarName]
-		// d. Render the template for feature set
-		tmpl := prepareTemplateForFeature(args.TemplateDir, featureSet, properties)
-		buffer := bytes.Buffer{}
-		err := tmpl.Execute(&buffer, tc)
+	arguments, legacy := legacyArguments(os.Args[1:])
+	if legacy {
+		fmt.Fprintf(os.Stderr, "the config dir as first argument is deprecated, use --config-dir %s\n", arguments[1])
+	}
+	args, parser, err := parseArgs(arguments, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logger, err := newLogger(os.Stderr, args.LogLevel, args.LogFormat)
+	if err != nil {
+		parser.Fail(err.Error())
+	}
+	slog.SetDefault(logger)
+	slog.Debug("arguments parsed", "args", dump(args.Args))
+
+	if len(args.FeatureFile) == 0 {
+		parser.Fail("--feature-file is required")
+	}
+	if args.FeatureMappingFile == "" {
+		parser.Fail("--feature-mapping-file is required")
+	}
+	if args.Render != nil || args.Validate != nil || parser.Subcommand() == nil {
+		if args.TemplateDir == "" {
+			parser.Fail("--template-dir is required")
+		}
+	}
+	switch {
+	case args.Validate != nil:
+		err = args.Validate.Run(os.Stdout, logger, args.Args)
+	case args.List != nil:
+		err = args.List.Run(os.Stdout, logger, args.Args)
+	case args.Explain != nil:
+		err = args.Explain.Run(os.Stdout, logger, args.Args)
+	case args.Graph != nil:
+		err = args.Graph.Run(os.Stdout, logger, args.Args)
+	default:
+		// the watcher follows the changes of the project file
+		reload := func() (Args, error) {
+			args, _, err := parseArgs(arguments, false)
+			return args.Args, err
+		}
+		err = RenderCommand{reload: reload}.Run(os.Stdout, logger, args.Args)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// The names of the subcommands, see Commands
+var subcommandNames = []string{"render", "validate", "list", "explain", "graph"}
+
+// parseArgs parses the arguments over the project file found from the config dir of the command line, the values of
+// the project file are the defaults of the second parsing so the command line takes precedence. With must, a parse
+// error prints the usage and exits like arg.MustParse.
+func parseArgs(arguments []string, must bool) (cliArgs, *arg.Parser, error) {
+	parse := func(dest *cliArgs) (*arg.Parser, error) {
+		parser, err := arg.NewParser(arg.Config{}, dest)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		fmt.Printf("== BEGIN OUTPUT ==\n%s", buffer.String())
+		if must {
+			parser.MustParse(arguments)
+			return parser, nil
+		}
+		return parser, parser.Parse(arguments)
+	}
+	var args, cli cliArgs
+	if _, err := parse(&cli); err != nil {
+		return args, nil, err
+	}
+	if err := applyProjectFile(&args.Args, cli.ConfigDir, cli.Profile); err != nil {
+		return args, nil, err
 	}
+	parser, err := parse(&args)
+	if err != nil {
+		return args, nil, err
+	}
+	replaceLists(&args.Args, cli.Args)
+	return args, parser, nil
+}
 
+// legacyArguments turns a first argument naming a dir rather than a subcommand, the config dir before --config-dir
+// existed, into --config-dir. It returns true if it did.
+func legacyArguments(arguments []string) ([]string, bool) {
+	if len(arguments) == 0 || strings.HasPrefix(arguments[0], "-") || slices.Contains(subcommandNames, arguments[0]) {
+		return arguments, false
+	}
+	if info, err := os.Stat(arguments[0]); err != nil || !info.IsDir() {
+		return arguments, false
+	}
+	return append([]string{"--config-dir", arguments[0]}, arguments[1:]...), true
 }
 
-func prepareTemplateForFeature(templateDir string, featureSet string, properties []interface{}) *template.Template {
-	tmplFile := path.Join(templateDir, fmt.Sprintf("%s.tmpl", featureSet))
-	f, err := os.Open(tmplFile)
+// Render every template of the feature set with the same context, the front-matter of each template decides how.
+// The template files read are recorded by the tracker, if any. The context is only read, so the feature sets can be
+// rendered concurrently.
+func renderFeatureSet(context map[string]interface{}, tracker *FileTracker, featureSet string, tc map[string]interface{}) []RenderedOutput {
+	args := context["args"].(Args)
+	files, err := featureSetTemplates(tracker.FS(args.TemplateDir), featureSet)
 	if err != nil {
 		panic(err)
 	}
-	defer f.Close()
-	functions := prepareTemplateFunctions(properties)
-	bytes, err := io.ReadAll(f)
-	if err != nil {
-		panic(err)
+	outputs := make([]RenderedOutput, 0, len(files))
+	for _, file := range files {
+		tmpl, frontMatter := prepareTemplateForFeature(context, tracker, file)
+		if missing := missingProperties(frontMatter.Required, PropertiesLookup{properties: context["properties"].([]interface{})}); len(missing) > 0 {
+			panic(fmt.Sprintf("%s: required properties not defined: %v", file, missing))
+		}
+		if frontMatter.Validate == "" && args.ValidateOutput {
+			frontMatter.Validate = "auto"
+		}
+		frontMatter.Format = frontMatter.Format || args.FormatOutput
+		rendered, err := renderTemplate(file, tmpl, frontMatter, tc, propertiesLookup(context))
+		var validationError *OutputValidationError
+		if errors.As(err, &validationError) {
+			validationError.locateTemplateLine(tracker.FS(args.TemplateDir))
+		}
+		if err != nil {
+			panic(err)
+		}
+		outputs = append(outputs, rendered...)
 	}
-	tmpl, err := template.New(featureSet).Funcs(functions).Parse(string(bytes))
+	return outputs
+}
+
+// Load the template file, the front-matter is stripped from the template and returned for the render step. The
+// partials are parsed once for all the feature sets, see TemplateCache.
+func prepareTemplateForFeature(context map[string]interface{}, tracker *FileTracker, file string) (Executable, FrontMatter) {
+	tmpl, frontMatter, err := context["templates"].(*TemplateCache).Load(file, tracker)
 	if err != nil {
 		panic(err)
 	}
-	return tmpl
+	return tmpl, frontMatter
 }
 
-func prepareTemplateFunctions(properties []interface{}) template.FuncMap {
-	lookup := PropertiesLookup{properties: properties}
-	functions := template.FuncMap{
-		"hasProperty": lookup.HasProperty,
-		"getProperty": lookup.GetProperty,
-		"add": func(a, b int) int {
-			return a + b
-		},
-	}
-	return functions
+func prepareTemplateFunctions(lookup PropertiesLookup) template.FuncMap {
+	return functionMap(templateFunctions(lookup))
 }
 
-// Read the raw feature file
-func readFeatures(context map[string]interface{}) interface{} {
-	step := PlainTextFileInputSource{
-		path:          context["args"].(Args).FeatureFile,
-		ignoreComment: true,
-		trim:          true,
-	}
-	value1, err := step.Provide(os.DirFS(context["args"].(Args).ConfigDir))
-	if err != nil {
-		panic(err)
+// Read the raw feature files, the features of all files are concatenated. The lines adding and removing the features
+// are recorded by the report, if any.
+func readFeatures(context map[string]interface{}, files []string, report *FeatureListReport) interface{} {
+	records := make([]string, 0)
+	for _, f := range files {
+		step := FeatureListInputSource{
+			path:     f,
+			groups:   context["feature-groups"].(map[string][]string),
+			warnings: contextWarnings(context),
+			report:   report,
+		}
+		value, err := step.Provide(fileTracker(context).FS(context["args"].(Args).ConfigDir))
+		if err != nil {
+			panic(err)
+		}
+		records = append(records, value.([]string)...)
 	}
-	return value1
+	return records
 }
 
 func readFeatureMapping(context map[string]interface{}) interface{} {
 	step := PropertiesInputSource{
 		path: context["args"].(Args).FeatureMappingFile,
 	}
-	value, err := step.Provide(os.DirFS(context["args"].(Args).ConfigDir))
+	value, err := step.Provide(fileTracker(context).FS(context["args"].(Args).ConfigDir))
 	if err != nil {
 		panic(err)
 	}
 	return value
 }
 
-func convertFeatureNames(context map[string]interface{}) interface{} {
-	step := ListMappingTransformer{
-		mapping: context["feature-mapping"].(map[string]string),
+// Convert the names of a feature list by the mapping without warnings, the mapping keys the list doesn't use and the
+// conflicts are reported once by the convert stage of the features
+func convertFeatureNames(context map[string]interface{}, contextVarName string) interface{} {
+	mapping := ListMappingTransformer{
+		mapping:  context["feature-mapping"].(map[string]string),
+		chain:    context["args"].(Args).ChainMapping,
+		warnings: ignoredWarnings(),
 	}
-	v, err := step.Transform((context)["raw-features"])
+	v, err := mapping.Transform(context[contextVarName])
 	if err != nil {
 		panic(err)
 	}
 	return v
 }
 
+// Transform the raw features by the feature chain
+func transformFeatures(context map[string]interface{}) interface{} {
+	value, err := featureChain(context).Transform(context["raw-features"])
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// The steps 4 to 6 as one chain, the optional stages are skipped unless enabled by the arguments:
+//
+//  4. convert: convert feature names using the mapping
+//     distinct: remove the duplicates
+//     a. exclude: remove the features listed in the exclude files
+//  5. expand: expand according to configuration in config.yaml
+//  6. enrich: enrich the features with the rows of the enrich file
+//     a. decode: decode the features into the typed model
+func featureChain(context map[string]interface{}) *ChainTransformer {
+	args := context["args"].(Args)
+	chain := Chain().
+		Stage("convert", featureNameMapping(context)).
+		Stage("distinct", distinctFeatures(context)).
+		Stage("exclude", ListSetTransformer{
+			other:     context["excluded-features"],
+			keyMapper: SelfMapper,
+			operation: Difference,
+		}).
+		Stage("expand", ListExpandTransformer{
+			dataByKey:   (context["config"]).(map[interface{}]interface{}),
+			keyMapper:   IdentityMapper,
+			keepKeyName: true,
+			warnings:    contextWarnings(context),
+		}).
+		Stage("enrich", JoinTransformer{
+			right:     context["enrich"],
+			leftKey:   StringMapMapper("Name"),
+			rightKey:  StringMapMapper(args.EnrichKey),
+			joinType:  LeftJoin,
+			collision: KeepLeft,
+			warnings:  contextWarnings(context),
+		}).
+		Stage("decode", FeatureDecodeTransformer{
+			extraKeys: enrichKeys(context),
+		})
+	if len(args.ExcludeFeatureFile) == 0 {
+		chain.Skip("exclude")
+	}
+	if args.EnrichFile == "" {
+		chain.Skip("enrich")
+	}
+	if !args.TypedFeatures {
+		chain.Skip("decode")
+	}
+	return chain
+}
+
+func featureNameMapping(context map[string]interface{}) Transformer {
+	return ListMappingTransformer{
+		mapping:  context["feature-mapping"].(map[string]string),
+		chain:    context["args"].(Args).ChainMapping,
+		warnings: contextWarnings(context),
+	}
+}
+
+func distinctFeatures(context map[string]interface{}) Transformer {
+	policy, err := ParseDuplicatePolicy(context["args"].(Args).DuplicatePolicy)
+	if err != nil {
+		panic(err)
+	}
+	// The feature names are deduplicated before the expand, there are no fields to merge yet
+	if policy == MergeDuplicates {
+		panic(errors.New("duplicate policy 'merge' can't be used on feature names, use first, last or error"))
+	}
+	return ListDistinctTransformer{
+		keyMapper: SelfMapper,
+		policy:    policy,
+	}
+}
+
+// Read the config file, validated against the bundled schema and the schema overrides
 func readConfigFile(context map[string]interface{}) interface{} {
-	step := YamlInputSource{
-		path: context["args"].(Args).ConfigFile,
+	args := context["args"].(Args)
+	var step InputSource = YamlInputSource{
+		path:   args.ConfigFile,
+		logger: contextLogger(context),
+	}
+	if !args.NoConfigValidation {
+		step = ValidatingInputSource{source: step, schema: readConfigSchema(context), name: args.ConfigFile}
+	}
+	value, err := step.Provide(fileTracker(context).FS(args.ConfigDir))
+	var schemaErrors SchemaErrors
+	if errors.As(err, &schemaErrors) && slices.ContainsFunc(schemaErrors, func(e SchemaError) bool {
+		return strings.HasPrefix(e.Message, "unknown property")
+	}) {
+		err = fmt.Errorf("%w\ncustom keys must start with x-, --config-schema can allow them and --no-config-validation "+
+			"skips the validation", err)
 	}
-	value, err := step.Provide(os.DirFS(context["args"].(Args).ConfigDir))
 	if err != nil {
 		panic(err)
 	}
 	return value
 }
 
-func expandFeatureByConfig(context map[string]interface{}) interface{} {
-	step := ListExpandTransformer{
-		dataByKey:   (context["config"]).(map[interface{}]interface{}),
-		keyMapper:   IdentityMapper,
-		keepKeyName: true,
+// The bundled schema of the config file with the --config-schema overrides merged in order
+func readConfigSchema(context map[string]interface{}) *Schema {
+	args := context["args"].(Args)
+	overrides := make([][]byte, 0, len(args.ConfigSchema))
+	for _, file := range args.ConfigSchema {
+		override, err := fs.ReadFile(fileTracker(context).FS(args.ConfigDir), file)
+		if err != nil {
+			panic(fmt.Sprintf("Error reading %s: %v", file, err))
+		}
+		overrides = append(overrides, override)
 	}
-	value, err := step.Transform(context["features"])
+	schema, err := loadSchema(defaultConfigSchema, overrides...)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// Remove the feature groups from the config and return them as map[string][]string
+func splitFeatureGroups(context map[string]interface{}) map[string][]string {
+	config := context["config"].(map[interface{}]interface{})
+	groups := make(map[string][]string)
+	value, ok := config[featureGroupsKey]
+	if !ok {
+		return groups
+	}
+	delete(config, featureGroupsKey)
+	definitions, ok := value.(map[string]interface{})
+	if !ok {
+		panic(fmt.Sprintf("%s in %s must be a map of lists", featureGroupsKey, context["args"].(Args).ConfigFile))
+	}
+	for name, entries := range definitions {
+		list, ok := entries.([]interface{})
+		if !ok {
+			panic(fmt.Sprintf("Group '%s' in %s must be a list", name, context["args"].(Args).ConfigFile))
+		}
+		for _, entry := range list {
+			groups[name] = append(groups[name], fmt.Sprint(entry))
+		}
+	}
+	return groups
+}
+
+func readEnrichFile(context map[string]interface{}) interface{} {
+	step := CsvFileInputSource{
+		path:     context["args"].(Args).EnrichFile,
+		headers:  context["args"].(Args).EnrichHeaders,
+		warnings: contextWarnings(context),
+	}
+	value, err := step.Provide(fileTracker(context).FS(context["args"].(Args).ConfigDir))
 	if err != nil {
 		panic(err)
 	}
 	return value
 }
 
+// The columns of the enrich file, they are kept as extras of the typed features
+func enrichKeys(context map[string]interface{}) []string {
+	extraKeys := make([]string, 0)
+	if rows, ok := context["enrich"].([]map[string]interface{}); ok {
+		for _, row := range rows {
+			for key := range row {
+				if !slices.Contains(extraKeys, key) {
+					extraKeys = append(extraKeys, key)
+				}
+			}
+		}
+	}
+	return extraKeys
+}
+
 // Read properties specified in the input arguments
 func readProperties(context map[string]interface{}) []interface{} {
 	files := context["args"].(Args).PropertyFiles
@@ -166,7 +442,7 @@ func readProperties(context map[string]interface{}) []interface{} {
 		step := PropertiesInputSource{
 			path: f,
 		}
-		properties, err := step.Provide(os.DirFS(context["args"].(Args).ConfigDir))
+		properties, err := step.Provide(fileTracker(context).FS(context["args"].(Args).ConfigDir))
 		if err != nil {
 			panic(err)
 		}
@@ -175,24 +451,49 @@ func readProperties(context map[string]interface{}) []interface{} {
 	return list
 }
 
-func filterFeatureByFeatureSet(context map[string]interface{}, featureSet string) interface{} {
-	step := ListFilterTransformer{
-		predicate: MapValuePredicate("feature-set", featureSet),
+// The lookup of the properties, the lookups of the templates are counted for the report
+func propertiesLookup(context map[string]interface{}) PropertiesLookup {
+	usage, _ := context["property-usage"].(*PropertyUsage)
+	return PropertiesLookup{properties: context["properties"].([]interface{}), usage: usage}
+}
+
+// The key path of the features naming their feature sets, --group-by
+func groupKeyPath(args Args) string {
+	if args.GroupBy == "" {
+		return "feature-set"
+	}
+	return args.GroupBy
+}
+
+func groupFeatureByFeatureSet(context map[string]interface{}) map[string][]interface{} {
+	step := GroupByTransformer{
+		keyMapper:  MapPathMapper(groupKeyPath(context["args"].(Args))),
+		multiValue: true,
+		warnings:   contextWarnings(context),
 	}
 	value, err := step.Transform(context["features"])
 	if err != nil {
 		panic(err)
 	}
-	return value
+	return value.(map[string][]interface{})
 }
 
-func sortFeatureSetByPriority(context map[string]interface{}, contextVarName string) interface{} {
-	step := ListStringSortTransformer{
-		mapper: MapValueStringMapper("priority"),
+// Decide the feature sets to render. With --all-feature-sets every group that has a template is rendered in name
+// order, otherwise the feature sets listed by --feature-set are rendered as given. The error is the one of the
+// feature sets without template when their policy is error.
+func selectFeatureSets(context map[string]interface{}) ([]string, error) {
+	args := context["args"].(Args)
+	if !args.AllFeatureSets {
+		return args.FeatureSet, nil
 	}
-	value, err := step.Transform(context[contextVarName])
-	if err != nil {
-		panic(err)
+	featureSets := make([]string, 0)
+	for featureSet := range context["feature-sets"].(map[string][]interface{}) {
+		if _, err := featureSetTemplates(fileTracker(context).FS(args.TemplateDir), featureSet); err != nil {
+			contextWarnings(context).Warnf(WarnNoTemplate, "Feature set '%s' has no template, skipped", featureSet)
+			continue
+		}
+		featureSets = append(featureSets, featureSet)
 	}
-	return value
+	slices.Sort(featureSets)
+	return featureSets, contextWarnings(context).Err()
 }
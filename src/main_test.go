@@ -0,0 +1,78 @@
+package main
+
+import (
+	"github.com/alexflint/go-arg"
+	"reflect"
+	"testing"
+)
+
+// The list flags take one value each, the subcommand and its arguments after them are not swallowed.
+func TestArgs_ListFlagsBeforeSubcommand(t *testing.T) {
+	var cli struct {
+		Args
+		Commands
+	}
+	parser, err := arg.NewParser(arg.Config{}, &cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arguments := []string{"--feature-file", "one.txt", "--feature-file", "two.txt", "--feature-set", "other", "explain", "Foo"}
+	if err := parser.Parse(arguments); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !reflect.DeepEqual(cli.FeatureFile, []string{"one.txt", "two.txt"}) || !reflect.DeepEqual(cli.FeatureSet, []string{"other"}) {
+		t.Errorf("Parse() lists = %v, %v", cli.FeatureFile, cli.FeatureSet)
+	}
+	if cli.Explain == nil || cli.Explain.Feature != "Foo" {
+		t.Errorf("Parse() explain = %+v", cli.Explain)
+	}
+}
+
+func TestReplaceLists(t *testing.T) {
+	args := Args{FeatureFile: []string{"project.txt", "cli.txt"}, PropertyFiles: []string{"project.properties"}, OutputDir: "out"}
+	replaceLists(&args, Args{FeatureFile: []string{"cli.txt"}})
+	want := Args{FeatureFile: []string{"cli.txt"}, PropertyFiles: []string{"project.properties"}, OutputDir: "out"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("replaceLists() = %+v, want %+v", args, want)
+	}
+}
+
+func TestLegacyArguments(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		arguments []string
+		want      []string
+		wantOk    bool
+	}{
+		{name: "config dir first", arguments: []string{dir, "--feature-file", "f.txt"}, want: []string{"--config-dir", dir, "--feature-file", "f.txt"}, wantOk: true},
+		{name: "subcommand", arguments: []string{"list", "features"}, want: []string{"list", "features"}},
+		{name: "flag", arguments: []string{"--config-dir", dir}, want: []string{"--config-dir", dir}},
+		{name: "not a dir", arguments: []string{"missing"}, want: []string{"missing"}},
+		{name: "none", arguments: []string{}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := legacyArguments(tt.arguments)
+			if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOk {
+				t.Errorf("legacyArguments() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+// A flag set to true by the project file is turned off with =false.
+func TestArgs_BoolFlagOff(t *testing.T) {
+	var cli struct {
+		Args
+		Commands
+	}
+	cli.TypedFeatures = true
+	parser, err := arg.NewParser(arg.Config{}, &cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parser.Parse([]string{"--typed-features=false"}); err != nil || cli.TypedFeatures {
+		t.Errorf("Parse() = %v, typed features %v", err, cli.TypedFeatures)
+	}
+}
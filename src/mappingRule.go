@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MappingRule maps a feature name to a new name. The key of a rule is one of
+//
+//	Foo           exact match
+//	legacy-*      glob, * matches any text and ? a single character, each wildcard is a capture group
+//	/^old-(.+)$/  regular expression enclosed in slashes
+//
+// The value replaces the whole name, $1, $2... are substituted with the capture groups. Unlike regexp.Expand the
+// digits end the reference, so "$1V" is the first group followed by "V".
+// An empty value drops the feature.
+type MappingRule struct {
+	key         string
+	replacement string
+	// nil for exact match rules
+	pattern *regexp.Regexp
+}
+
+// A numbered group reference in a replacement, e.g. $1
+var groupReference = regexp.MustCompile(`\$(\d+)`)
+
+// compileMappingRules compiles the mapping into the exact match rules by key and the pattern rules ordered by key.
+func compileMappingRules(mapping map[string]string) (exact map[string]MappingRule, patterns []MappingRule, err error) {
+	exact = make(map[string]MappingRule)
+	patterns = make([]MappingRule, 0)
+	for key, replacement := range mapping {
+		rule := MappingRule{key: key, replacement: replacement}
+		switch {
+		case len(key) > 2 && strings.HasPrefix(key, "/") && strings.HasSuffix(key, "/"):
+			rule.pattern, err = regexp.Compile(key[1 : len(key)-1])
+		case strings.ContainsAny(key, "*?"):
+			rule.pattern, err = regexp.Compile(globToRegexp(key))
+		default:
+			exact[key] = rule
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid mapping key '%s': %w", key, err)
+		}
+		rule.replacement = groupReference.ReplaceAllString(replacement, "$${$1}")
+		patterns = append(patterns, rule)
+	}
+	slices.SortFunc(patterns, func(a, b MappingRule) int {
+		return strings.Compare(a.key, b.key)
+	})
+	return exact, patterns, nil
+}
+
+// globToRegexp converts a glob to an anchored regular expression, each wildcard becomes a capture group.
+func globToRegexp(glob string) string {
+	sb := strings.Builder{}
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString("(.*)")
+		case '?':
+			sb.WriteString("(.)")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// Apply returns the new name if the rule matches the input.
+func (rule MappingRule) Apply(input string) (string, bool) {
+	if rule.pattern == nil {
+		if input != rule.key {
+			return "", false
+		}
+		return rule.replacement, true
+	}
+	match := rule.pattern.FindStringSubmatchIndex(input)
+	if match == nil {
+		return "", false
+	}
+	return string(rule.pattern.ExpandString(nil, rule.replacement, input, match)), true
+}
+
+// MappingConflict records an input matched by more than one pattern rule, the first key is the one applied.
+type MappingConflict struct {
+	Input string
+	Keys  []string
+}
+
+// MappingReport collects the findings of ListMappingTransformer.
+type MappingReport struct {
+	// UnusedKeys are the mapping keys that matched no input, in key order.
+	UnusedKeys []string
+	// Conflicts are the inputs matched by several pattern rules.
+	Conflicts []MappingConflict
+	// Dropped are the inputs mapped to an empty value.
+	Dropped []string
+	// Renamed maps the renamed inputs to their rename path, e.g. foo: [foo bar baz] with chaining.
+	Renamed map[string][]string
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMappingRule_Apply(t *testing.T) {
+	type args struct {
+		input string
+	}
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		args    args
+		want    string
+		wantOk  bool
+		wantErr bool
+	}{
+		{
+			name:   "exact",
+			key:    "bar",
+			value:  "Bar",
+			args:   args{input: "bar"},
+			want:   "Bar",
+			wantOk: true,
+		},
+		{
+			name:   "exact, not matched",
+			key:    "bar",
+			value:  "Bar",
+			args:   args{input: "barbar"},
+			wantOk: false,
+		},
+		{
+			name:   "glob with captures",
+			key:    "svc-*-v?",
+			value:  "$1V$2",
+			args:   args{input: "svc-auth-v2"},
+			want:   "authV2",
+			wantOk: true,
+		},
+		{
+			name:   "glob is anchored",
+			key:    "svc-*",
+			value:  "$1",
+			args:   args{input: "my-svc-auth"},
+			wantOk: false,
+		},
+		{
+			name:   "glob quotes regex characters",
+			key:    "a.b*",
+			value:  "x",
+			args:   args{input: "aXb"},
+			wantOk: false,
+		},
+		{
+			name:   "regex replaces the whole name",
+			key:    "/old-([a-z]+)/",
+			value:  "New$1",
+			args:   args{input: "very-old-foo"},
+			want:   "Newfoo",
+			wantOk: true,
+		},
+		{
+			name:    "invalid regex",
+			key:     "/[/",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exact, patterns, err := compileMappingRules(map[string]string{tt.key: tt.value})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileMappingRules() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			rule, ok := exact[tt.key]
+			if !ok {
+				rule = patterns[0]
+			}
+			got, gotOk := rule.Apply(tt.args.input)
+			if got != tt.want || gotOk != tt.wantOk {
+				t.Errorf("Apply() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/magiconair/properties"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The output formats by file extension, used when the validate setting is auto
+var outputFormatsByExtension = map[string]string{
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".json":       "json",
+	".properties": "properties",
+}
+
+// OutputValidationError is a rendered output that does not parse in its format.
+type OutputValidationError struct {
+	// The output path
+	Path   string
+	Format string
+	// The line of the rendered output, 0 if unknown
+	Line    int
+	Message string
+	// The template file, and the template line that most likely produced the line, 0 if unknown
+	TemplateFile string
+	TemplateLine int
+	// The rendered output
+	content []byte
+}
+
+func (e *OutputValidationError) Error() string {
+	msg := fmt.Sprintf("%s:%d: invalid %s: %s", e.Path, e.Line, e.Format, e.Message)
+	if e.TemplateLine > 0 {
+		msg += fmt.Sprintf(" (template %s:%d)", e.TemplateFile, e.TemplateLine)
+	} else if e.TemplateFile != "" {
+		msg += fmt.Sprintf(" (template %s)", e.TemplateFile)
+	}
+	return msg
+}
+
+// outputFormat resolves the validate setting of the front-matter, auto picks the format by the output extension.
+// An empty result means no validation.
+func outputFormat(outputPath string, validate string) (string, error) {
+	switch validate {
+	case "", "none":
+		return "", nil
+	case "auto":
+		return outputFormatsByExtension[path.Ext(outputPath)], nil
+	case "yaml", "json", "properties":
+		return validate, nil
+	}
+	return "", fmt.Errorf("unknown output format '%s'", validate)
+}
+
+// The line number in the errors of yaml.v3 and properties
+var errorLine = regexp.MustCompile(`(?i)line (\d+): `)
+
+// validateOutput parses the content in the format. It returns the line of the error, 0 if unknown.
+func validateOutput(format string, content []byte) (int, error) {
+	switch format {
+	case "yaml":
+		_, err := decodeYamlDocuments(content)
+		if err != nil {
+			return errorLineOf(strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+	case "json":
+		var v interface{}
+		if err := json.Unmarshal(content, &v); err != nil {
+			line := 0
+			var syntaxError *json.SyntaxError
+			if errors.As(err, &syntaxError) {
+				line = bytes.Count(content[:syntaxError.Offset], []byte("\n")) + 1
+			}
+			return line, err
+		}
+	case "properties":
+		if _, err := loadProperties(content); err != nil {
+			return errorLineOf(strings.TrimPrefix(err.Error(), "properties: "))
+		}
+	}
+	return 0, nil
+}
+
+// errorLineOf splits the line number from a parse error message.
+func errorLineOf(message string) (int, error) {
+	m := errorLine.FindStringSubmatch(message)
+	if m == nil {
+		return 0, errors.New(message)
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line, errors.New(strings.Replace(message, m[0], "", 1))
+}
+
+// decodeYamlDocuments decodes every document of a YAML stream.
+func decodeYamlDocuments(content []byte) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	documents := make([]*yaml.Node, 0)
+	for {
+		node := &yaml.Node{}
+		err := decoder.Decode(node)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, node)
+	}
+}
+
+// loadProperties parses a properties file without expanding the ${} references.
+func loadProperties(content []byte) (*properties.Properties, error) {
+	loader := properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+	return loader.LoadBytes(content)
+}
+
+// formatOutput re-formats the content canonically, so diffs of the output stay stable. YAML is indented by 2 spaces,
+// JSON is indented by 2 spaces and properties are written as key = value. Comments are kept in YAML only.
+func formatOutput(format string, content []byte) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	switch format {
+	case "yaml":
+		documents, err := decodeYamlDocuments(content)
+		if err != nil {
+			return nil, err
+		}
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		for _, document := range documents {
+			if err := encoder.Encode(document); err != nil {
+				return nil, err
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	case "json":
+		if err := json.Indent(&buffer, bytes.TrimSpace(content), "", "  "); err != nil {
+			return nil, err
+		}
+		buffer.WriteString("\n")
+	case "properties":
+		p, err := loadProperties(content)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.Write(&buffer, properties.UTF8); err != nil {
+			return nil, err
+		}
+	default:
+		return content, nil
+	}
+	return buffer.Bytes(), nil
+}
+
+// The actions of a template line
+var templateAction = regexp.MustCompile(`\{\{.*?}}`)
+
+// matchTemplateLine finds the line of the template source that most likely rendered the line: the line whose literal
+// text, without the actions, is found in the rendered line and is the longest. It returns 0 if no line matches.
+func matchTemplateLine(source []byte, rendered string) int {
+	best, bestScore := 0, 0
+	for i, line := range strings.Split(string(source), "\n") {
+		score := 0
+		for _, literal := range templateAction.Split(line, -1) {
+			literal = strings.TrimSpace(literal)
+			if len(literal) == 0 {
+				continue
+			}
+			if !strings.Contains(rendered, literal) {
+				score = 0
+				break
+			}
+			score += len(literal)
+		}
+		if score > bestScore {
+			best, bestScore = i+1, score
+		}
+	}
+	return best
+}
+
+// locateTemplateLine looks for the template line of the error in the template file and the partials it may use.
+func (e *OutputValidationError) locateTemplateLine(templates fs.FS) {
+	lines := strings.Split(string(e.content), "\n")
+	if e.Line < 1 || e.Line > len(lines) || strings.TrimSpace(lines[e.Line-1]) == "" {
+		return
+	}
+	rendered := lines[e.Line-1]
+	candidates := []string{e.TemplateFile}
+	partials, _ := fs.Glob(templates, partialPattern)
+	candidates = append(candidates, partials...)
+	if dir := path.Dir(e.TemplateFile); dir != "." {
+		partials, _ = fs.Glob(templates, path.Join(dir, partialPattern))
+		candidates = append(candidates, partials...)
+	}
+	file, line, bestScore := "", 0, 0
+	for _, candidate := range candidates {
+		source, err := fs.ReadFile(templates, candidate)
+		if err != nil {
+			continue
+		}
+		if n := matchTemplateLine(source, rendered); n > 0 {
+			score := len(strings.TrimSpace(templateAction.ReplaceAllString(strings.Split(string(source), "\n")[n-1], "")))
+			if score > bestScore {
+				file, line, bestScore = candidate, n, score
+			}
+		}
+	}
+	if file != "" {
+		e.TemplateFile, e.TemplateLine = file, line
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		validate string
+		want     string
+		wantErr  bool
+	}{
+		{name: "not set", path: "one.yaml", validate: "", want: ""},
+		{name: "none", path: "one.yaml", validate: "none", want: ""},
+		{name: "auto, yaml", path: "one/deployment.yml", validate: "auto", want: "yaml"},
+		{name: "auto, json", path: "one.json", validate: "auto", want: "json"},
+		{name: "auto, properties", path: "one.properties", validate: "auto", want: "properties"},
+		{name: "auto, unknown extension", path: "one.txt", validate: "auto", want: ""},
+		{name: "explicit", path: "one", validate: "yaml", want: "yaml"},
+		{name: "unknown", path: "one", validate: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := outputFormat(tt.path, tt.validate)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("outputFormat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("outputFormat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		content  string
+		wantLine int
+		wantErr  bool
+	}{
+		{name: "yaml", format: "yaml", content: "---\na: 1\n---\nb: [1, 2]\n"},
+		{name: "yaml, bad indentation", format: "yaml", content: "a: 1\nb: 2\n  c: 3\n", wantLine: 3, wantErr: true},
+		{name: "yaml, second document", format: "yaml", content: "a: 1\n---\nb: c: d\n", wantLine: 3, wantErr: true},
+		{name: "json", format: "json", content: `{"a": [1, 2]}`},
+		{name: "json, trailing comma", format: "json", content: "{\n  \"a\": 1,\n}\n", wantLine: 3, wantErr: true},
+		{name: "properties", format: "properties", content: "a = 1\nb = ${missing}\n"},
+		{name: "properties, bad escape", format: "properties", content: "a = 1\nb = \\u12\n", wantLine: 2, wantErr: true},
+		{name: "no format", format: "", content: "{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := validateOutput(tt.format, []byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOutput() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if line != tt.wantLine {
+				t.Errorf("validateOutput() line = %v, want %v", line, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestFormatOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		want    string
+	}{
+		{name: "yaml", format: "yaml", content: "a:\n    - b: 1   # comment\n", want: "a:\n  - b: 1 # comment\n"},
+		{name: "yaml, documents", format: "yaml", content: "a: 1\n---\nb:    2\n", want: "a: 1\n---\nb: 2\n"},
+		{name: "json", format: "json", content: `{"a":[1,2]}`, want: "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n"},
+		{name: "properties", format: "properties", content: "b:2\na   =   ${b}\n", want: "b = 2\na = ${b}\n"},
+		{name: "no format", format: "", content: "as is  ", want: "as is  "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatOutput(tt.format, []byte(tt.content))
+			if err != nil {
+				t.Errorf("formatOutput() error = %v", err)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("formatOutput() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTemplateLine(t *testing.T) {
+	source := "features:\n{{- range .features }}\n  - Name: {{ .Name }}\n    Owner: {{ .Owner }}\n{{- end }}"
+	tests := []struct {
+		name     string
+		rendered string
+		want     int
+	}{
+		{name: "literal and action", rendered: "  - Name: Foo", want: 3},
+		{name: "longest literal wins", rendered: "   Owner: bar", want: 4},
+		{name: "no match", rendered: "something: else", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchTemplateLine([]byte(source), tt.rendered); got != tt.want {
+				t.Errorf("matchTemplateLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputValidationError_locateTemplateLine(t *testing.T) {
+	templates := fstest.MapFS{
+		"one.tmpl":       {Data: []byte("name: one\nfeatures:\n{{- range .features }}\n  - {{ template \"_item.tmpl\" . }}\n{{- end }}")},
+		"_item.tmpl":     {Data: []byte("{{ .Name }}\n   owner: {{ .Owner }}")},
+		"two/_item.tmpl": {Data: []byte("never: used")},
+	}
+	e := &OutputValidationError{
+		Path: "one", Format: "yaml", Line: 4, Message: "bad indentation", TemplateFile: "one.tmpl",
+		content: []byte("name: one\nfeatures:\n  - Foo\n   owner: bar\n"),
+	}
+	e.locateTemplateLine(templates)
+	if e.TemplateFile != "_item.tmpl" || e.TemplateLine != 2 {
+		t.Errorf("locateTemplateLine() got = %s:%d, want _item.tmpl:2", e.TemplateFile, e.TemplateLine)
+	}
+	if want := "one:4: invalid yaml: bad indentation (template _item.tmpl:2)"; e.Error() != want {
+		t.Errorf("Error() got = %v, want %v", e.Error(), want)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// pipelineStep is a named step of the pipeline, it reads its inputs from the context and stores its results in it.
+type pipelineStep struct {
+	name string
+	run  func(context map[string]interface{})
+	// The context keys of the main input and output, the report counts their entries
+	input  string
+	output string
+}
+
+// The steps preparing the features of every command, the render step runs per feature set after them.
+var pipelineSteps = []pipelineStep{
+	// 1. Read config.yaml from a YAML file, the feature groups are split from the features config
+	{name: "config", output: "config", run: func(context map[string]interface{}) {
+		context["config"] = readConfigFile(context)
+		context["feature-groups"] = splitFeatureGroups(context)
+	}},
+	// 2. Read features from feature list files, the lines adding and removing them are kept for explain
+	{name: "features", output: "raw-features", run: func(context map[string]interface{}) {
+		report := &FeatureListReport{}
+		context["raw-features"] = readFeatures(context, context["args"].(Args).FeatureFile, report)
+		context["feature-origins"] = report.Origins
+	}},
+	// 3. Read feature mapping from a properties file
+	{name: "mapping", output: "feature-mapping", run: func(context map[string]interface{}) {
+		context["feature-mapping"] = readFeatureMapping(context)
+	}},
+	// 4-6. Convert, deduplicate, exclude, expand and enrich the features, see featureChain
+	{name: "transform", input: "raw-features", output: "features", run: func(context map[string]interface{}) {
+		args := context["args"].(Args)
+		if len(args.ExcludeFeatureFile) > 0 {
+			context["raw-excluded-features"] = readFeatures(context, args.ExcludeFeatureFile, nil)
+			context["excluded-features"] = convertFeatureNames(context, "raw-excluded-features")
+		}
+		if args.EnrichFile != "" {
+			context["enrich"] = readEnrichFile(context)
+		}
+		context["features"] = transformFeatures(context)
+	}},
+	// 7. Read properties from property files
+	{name: "properties", output: "properties", run: func(context map[string]interface{}) {
+		context["properties"] = readProperties(context)
+	}},
+	// The templates are parsed on demand, the partials once for all the feature sets
+	{name: "templates", run: func(context map[string]interface{}) {
+		context["templates"] = NewTemplateCache(context["args"].(Args), propertiesLookup(context))
+	}},
+	// 8. Group the features by config#featureSet, or the --group-by key path
+	{name: "group", input: "features", output: "feature-sets", run: func(context map[string]interface{}) {
+		context["feature-sets"] = groupFeatureByFeatureSet(context)
+	}},
+}
+
+// runPipeline runs every pipeline step and returns the context, a step failing stops the pipeline with an error naming
+// the step. The steps log to the logger, the default one if nil, and record the files they read with the tracker, if
+// any. The warnings are collected and a warning whose policy is error fails the step raising it. With --report the
+// steps are reported, and with --dump-after or --dump-context the context is dumped after the step.
+func runPipeline(args Args, tracker *FileTracker, logger *slog.Logger) (map[string]interface{}, error) {
+	context := make(map[string]interface{})
+	context["args"] = args
+	context["logger"] = loggerOrDefault(logger)
+	policies, err := ParseWarningPolicies(args.WarningPolicy)
+	if err != nil {
+		return context, err
+	}
+	context["warnings"] = NewWarnings(contextLogger(context), policies)
+	context["property-usage"] = &PropertyUsage{}
+	if args.Report != "" {
+		context["report"] = NewRunReport()
+	}
+	if tracker != nil {
+		context["file-tracker"] = tracker
+	}
+	dumpAfter, err := dumpStep(args)
+	if err != nil {
+		return context, err
+	}
+	for _, step := range pipelineSteps {
+		start := time.Now()
+		err := catch(func() { step.run(context) })
+		if err == nil {
+			err = contextWarnings(context).Err()
+		}
+		contextReport(context).addStep(step, context, time.Since(start), err)
+		if err != nil {
+			return context, fmt.Errorf("step %s: %w", step.name, err)
+		}
+		if step.name == dumpAfter {
+			if err := dumpContext(context); err != nil {
+				return context, fmt.Errorf("dump after step %s: %w", step.name, err)
+			}
+		}
+	}
+	return context, nil
+}
+
+// prepareFeatureSet runs the per feature set steps and returns the data of the templates. The context is only read, so
+// the feature sets can be prepared concurrently.
+func prepareFeatureSet(context map[string]interface{}, featureSet string) map[string]interface{} {
+	// a. Pick the features of the feature set
+	// b. Sort the features based on config#priority
+	features := sortedFeatureSet(context, featureSet)
+	// c. Prepare the context for rendering
+	properties := context["properties"].([]interface{})
+	tc := make(map[string]interface{})
+	tc["features"] = features
+	tc["featureSet"] = featureSet
+	tc["properties"] = PropertiesLookup{properties: properties}.Merged()
+	contextLogger(context).Debug("feature set prepared", "featureSet", featureSet, "data", dump(tc))
+	return tc
+}
+
+// renderedFeatureSet is the result of rendering a feature set
+type renderedFeatureSet struct {
+	featureSet string
+	outputs    []RenderedOutput
+	// The template files read
+	templates []string
+	// The stamps of the template files, taken before they were read
+	stamps map[string]fileStamp
+	// The number of features of the feature set
+	features int
+	duration time.Duration
+	err      error
+}
+
+// renderFeatureSets renders the feature sets concurrently, --jobs at a time. The context is only read and the results
+// are in the order of the feature sets, a failed feature set doesn't stop the others.
+func renderFeatureSets(context map[string]interface{}, featureSets []string) []renderedFeatureSet {
+	jobs := context["args"].(Args).Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	results := make([]renderedFeatureSet, len(featureSets))
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, featureSet := range featureSets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			start := time.Now()
+			tracker := NewFileTracker()
+			result := renderedFeatureSet{featureSet: featureSet}
+			result.err = catch(func() {
+				tc := prepareFeatureSet(context, featureSet)
+				result.features = len(tc["features"].([]interface{}))
+				// d. Render the templates of the feature set
+				result.outputs = renderFeatureSet(context, tracker, featureSet, tc)
+			})
+			if result.err != nil {
+				result.err = fmt.Errorf("feature set %s: %w", featureSet, result.err)
+			}
+			result.templates = tracker.Files()
+			result.stamps = tracker.Stamps()
+			result.duration = time.Since(start)
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	claimOutputs(results, map[string]string{})
+	for _, result := range results {
+		entry := FeatureSetReport{
+			Name:       result.featureSet,
+			DurationMs: milliseconds(result.duration),
+			Features:   result.features,
+			Outputs:    len(result.outputs),
+		}
+		if result.err != nil {
+			entry.Error = result.err.Error()
+		}
+		contextReport(context).addFeatureSet(entry)
+	}
+	return results
+}
+
+// claimOutputs fails the rendered feature sets with an output path already claimed, by an earlier feature set or
+// template or in the owners, and adds the paths of the others to the owners. The first one in the order wins, not the
+// first one rendered.
+func claimOutputs(results []renderedFeatureSet, owners map[string]string) {
+	for i, result := range results {
+		if result.err != nil {
+			continue
+		}
+		claimed := make(map[string]string, len(result.outputs))
+		for _, output := range result.outputs {
+			owner, ok := owners[output.Path]
+			if !ok {
+				owner, ok = claimed[output.Path]
+			}
+			if ok {
+				results[i].err = fmt.Errorf("feature set %s: output %s of %s is also rendered by %s", result.featureSet,
+					output.Path, output.Template, owner)
+				break
+			}
+			claimed[output.Path] = fmt.Sprintf("feature set %s, %s", result.featureSet, output.Template)
+		}
+		if results[i].err == nil {
+			maps.Copy(owners, claimed)
+		}
+	}
+}
+
+// writeFeatureSet writes the outputs of the rendered feature set to the output dir, or to w without one, or returns its
+// error. It returns the number of files written, the files whose content didn't change are not.
+func writeFeatureSet(w io.Writer, context map[string]interface{}, result renderedFeatureSet) (int, error) {
+	if result.err != nil {
+		return 0, result.err
+	}
+	outputDir := context["args"].(Args).OutputDir
+	written := 0
+	for _, output := range result.outputs {
+		changed, err := writeOutput(w, outputDir, output)
+		if err != nil {
+			return written, fmt.Errorf("feature set %s: %w", result.featureSet, err)
+		}
+		contextReport(context).addFile(result.featureSet, outputDir, output, changed)
+		if changed {
+			written++
+		}
+	}
+	return written, nil
+}
+
+// catch runs the function and returns its panic as an error, the steps panic on errors.
+func catch(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	f()
+	return nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderFeatureSets(t *testing.T) {
+	dir := t.TempDir()
+	featureSets := []string{"d", "c", "missing", "b", "a"}
+	context := map[string]interface{}{
+		"args":         Args{TemplateDir: dir, Jobs: 2},
+		"properties":   []interface{}{map[string]interface{}{"env": "test"}},
+		"feature-sets": map[string][]interface{}{},
+	}
+	context["templates"] = NewTemplateCache(context["args"].(Args), propertiesLookup(context))
+	for _, featureSet := range featureSets {
+		context["feature-sets"].(map[string][]interface{})[featureSet] = []interface{}{
+			map[string]interface{}{"Name": "Second", "priority": "2"},
+			map[string]interface{}{"Name": "First", "priority": "1"},
+		}
+		if featureSet == "missing" {
+			continue
+		}
+		template := `{{ getProperty "env" }} {{ .featureSet }}:{{ range .features }} {{ .Name }}{{ end }}`
+		if err := os.WriteFile(filepath.Join(dir, featureSet+".tmpl"), []byte(template), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results := renderFeatureSets(context, featureSets)
+	if len(results) != len(featureSets) {
+		t.Fatalf("renderFeatureSets() = %d results, want %d", len(results), len(featureSets))
+	}
+	for i, result := range results {
+		featureSet := featureSets[i]
+		if result.featureSet != featureSet {
+			t.Errorf("result %d is feature set %s, want %s", i, result.featureSet, featureSet)
+		}
+		if featureSet == "missing" {
+			if result.err == nil || !strings.HasPrefix(result.err.Error(), "feature set missing: No template found") {
+				t.Errorf("feature set missing: error = %v", result.err)
+			}
+			continue
+		}
+		if result.err != nil {
+			t.Errorf("feature set %s: error = %v", featureSet, result.err)
+			continue
+		}
+		want := "test " + featureSet + ": First Second"
+		if len(result.outputs) != 1 || string(result.outputs[0].Content) != want {
+			t.Errorf("feature set %s: outputs = %v, want %q", featureSet, result.outputs, want)
+		}
+		if len(result.templates) == 0 {
+			t.Errorf("feature set %s: no template recorded", featureSet)
+		}
+	}
+}
+
+func TestGroupFeatureByFeatureSet(t *testing.T) {
+	features := []interface{}{
+		map[string]interface{}{"Name": "Foo", "feature-set": "one", "owner": map[string]interface{}{"team": "web"}},
+		map[string]interface{}{"Name": "Bar", "feature-set": []interface{}{"one", "two"}, "owner": map[string]interface{}{"team": "api"}},
+		Feature{Name: "Baz", FeatureSet: []string{"two"}, Extras: map[string]interface{}{"owner": map[string]interface{}{"team": "web"}}},
+	}
+	tests := []struct {
+		name    string
+		groupBy string
+		want    map[string][]string
+	}{
+		{name: "default key", groupBy: "", want: map[string][]string{"one": {"Foo", "Bar"}, "two": {"Bar", "Baz"}}},
+		{name: "key path", groupBy: "owner.team", want: map[string][]string{"web": {"Foo", "Baz"}, "api": {"Bar"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context := map[string]interface{}{"args": Args{GroupBy: tt.groupBy}, "features": features}
+			groups := groupFeatureByFeatureSet(context)
+			got := make(map[string][]string)
+			for group, members := range groups {
+				for _, feature := range members {
+					got[group] = append(got[group], fmt.Sprint(StringMapMapper("Name")(feature)))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupFeatureByFeatureSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The --group-by example of the README passes the default schema.
+func TestGroupBy_ReadmeExample(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml":        "Foo:\n  priority: A01\n  x-owner:\n    team: web\n",
+		"features.txt":       "Foo\n",
+		"mapping.properties": "",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, typed := range []bool{false, true} {
+		args := Args{
+			ConfigDir:          dir,
+			FeatureFile:        []string{"features.txt"},
+			FeatureMappingFile: "mapping.properties",
+			ConfigFile:         "config.yaml",
+			GroupBy:            "x-owner.team",
+			TypedFeatures:      typed,
+			EnrichKey:          "Name",
+		}
+		context, err := runPipeline(args, nil, nil)
+		if err != nil {
+			t.Fatalf("runPipeline(typed=%v) error = %v", typed, err)
+		}
+		groups := context["feature-sets"].(map[string][]interface{})
+		if len(groups) != 1 || len(groups["web"]) != 1 {
+			t.Errorf("feature sets (typed=%v) = %v, want Foo in web", typed, groups)
+		}
+	}
+}
+
+func TestConvertFeatureNames_NoWarnings(t *testing.T) {
+	policies, err := ParseWarningPolicies([]string{"all=error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := map[string]interface{}{
+		"args":                  Args{},
+		"feature-mapping":       map[string]string{"bar": "Bar", "baz": "Baz"},
+		"raw-excluded-features": []string{"bar"},
+		"warnings":              NewWarnings(nil, policies),
+	}
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	got := convertFeatureNames(context, "raw-excluded-features")
+	if !reflect.DeepEqual(got, []interface{}{"Bar"}) {
+		t.Errorf("convertFeatureNames() = %v", got)
+	}
+	if err := contextWarnings(context).Err(); err != nil {
+		t.Errorf("convertFeatureNames() warned: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("convertFeatureNames() logged: %s", logs.String())
+	}
+}
+
+func TestDistinctFeatures_Merge(t *testing.T) {
+	context := map[string]interface{}{"args": Args{DuplicatePolicy: "merge"}}
+	err := catch(func() { distinctFeatures(context) })
+	if err == nil || err.Error() != "duplicate policy 'merge' can't be used on feature names, use first, last or error" {
+		t.Errorf("distinctFeatures() error = %v", err)
+	}
+}
+
+func TestClaimOutputs(t *testing.T) {
+	results := []renderedFeatureSet{
+		{featureSet: "a", outputs: []RenderedOutput{{Path: "a.txt", Template: "a/one.tmpl"}, {Path: "all.txt", Template: "a/two.tmpl"}}},
+		{featureSet: "b", outputs: []RenderedOutput{{Path: "b.txt", Template: "b.tmpl"}, {Path: "all.txt", Template: "b.tmpl"}}},
+		{featureSet: "c", outputs: []RenderedOutput{{Path: "c.txt", Template: "c/one.tmpl"}, {Path: "c.txt", Template: "c/two.tmpl"}}},
+		{featureSet: "d", outputs: []RenderedOutput{{Path: "cached.txt", Template: "d.tmpl"}}},
+		{featureSet: "e", outputs: []RenderedOutput{{Path: "b.txt", Template: "e.tmpl"}}},
+	}
+	owners := map[string]string{"cached.txt": "feature set z"}
+	claimOutputs(results, owners)
+	want := []string{
+		"",
+		"feature set b: output all.txt of b.tmpl is also rendered by feature set a, a/two.tmpl",
+		"feature set c: output c.txt of c/two.tmpl is also rendered by feature set c, c/one.tmpl",
+		"feature set d: output cached.txt of d.tmpl is also rendered by feature set z",
+		// b failed, its outputs are not claimed
+		"",
+	}
+	for i, result := range results {
+		got := ""
+		if result.err != nil {
+			got = result.err.Error()
+		}
+		if got != want[i] {
+			t.Errorf("feature set %s: error = %q, want %q", result.featureSet, got, want[i])
+		}
+	}
+	if _, ok := owners["b.txt"]; !ok {
+		t.Errorf("owners = %v, want b.txt claimed by e", owners)
+	}
+}
+
+// Without an output dir, the outputs are printed to the writer of the command.
+func TestWriteFeatureSet_NoOutputDir(t *testing.T) {
+	context := map[string]interface{}{"args": Args{}}
+	result := renderedFeatureSet{featureSet: "one", outputs: []RenderedOutput{{Path: "one.txt", Content: []byte("foo\n")}}}
+	var buffer bytes.Buffer
+	written, err := writeFeatureSet(&buffer, context, result)
+	if err != nil || written != 1 {
+		t.Fatalf("writeFeatureSet() = %d, %v", written, err)
+	}
+	if want := "== BEGIN OUTPUT one.txt ==\nfoo\n"; buffer.String() != want {
+		t.Errorf("writeFeatureSet() printed %q, want %q", buffer.String(), want)
+	}
+}
+
+// The sample templates render the same with and without --typed-features.
+func TestRenderSamples(t *testing.T) {
+	render := func(typed bool) string {
+		args := Args{
+			ConfigDir:          "../samples/configs",
+			FeatureFile:        []string{"features.txt"},
+			FeatureMappingFile: "feature-rename.properties",
+			ConfigFile:         "config.yaml",
+			FeatureSet:         []string{"one"},
+			PropertyFiles:      []string{"one.properties"},
+			TemplateDir:        "../samples/templates",
+			TypedFeatures:      typed,
+			EnrichKey:          "Name",
+			WarningPolicy:      []string{"unused-config-key=ignore"},
+		}
+		context, err := runPipeline(args, nil, nil)
+		if err != nil {
+			t.Fatalf("runPipeline(typed=%v) error = %v", typed, err)
+		}
+		results := renderFeatureSets(context, []string{"one"})
+		if results[0].err != nil {
+			t.Fatalf("render(typed=%v) error = %v", typed, results[0].err)
+		}
+		return string(results[0].outputs[0].Content)
+	}
+	untyped, typed := render(false), render(true)
+	if !strings.Contains(untyped, "- name: foo_value1\n            value: 10") {
+		t.Errorf("untyped output misses the parameters:\n%s", untyped)
+	}
+	if typed != untyped {
+		t.Errorf("typed output = \n%s\nwant\n%s", typed, untyped)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// PostProcessor changes the rendered output of a template.
+type PostProcessor func(content []byte) ([]byte, error)
+
+// The blank lines following another blank line
+var repeatedBlankLines = regexp.MustCompile(`\n([ \t]*\n)+`)
+
+// postProcessors are the post-processors available to the post-process setting of the front-matter, by name.
+var postProcessors = map[string]PostProcessor{
+	// Removes the white space at the end of every line
+	"trim-trailing-space": func(content []byte) ([]byte, error) {
+		lines := bytes.Split(content, []byte("\n"))
+		for i, line := range lines {
+			lines[i] = bytes.TrimRight(line, " \t")
+		}
+		return bytes.Join(lines, []byte("\n")), nil
+	},
+	// Ends the output with exactly one new line
+	"final-newline": func(content []byte) ([]byte, error) {
+		return append(bytes.TrimRight(content, "\n"), '\n'), nil
+	},
+	// Replaces consecutive blank lines with a single one
+	"squeeze-blank-lines": func(content []byte) ([]byte, error) {
+		return repeatedBlankLines.ReplaceAll(content, []byte("\n\n")), nil
+	},
+}
+
+// postProcess applies the named post-processors to the content in order.
+func postProcess(names []string, content []byte) ([]byte, error) {
+	for _, name := range names {
+		processor, ok := postProcessors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown post-processor '%s'", name)
+		}
+		var err error
+		content, err = processor(content)
+		if err != nil {
+			return nil, fmt.Errorf("post-processor '%s': %w", name, err)
+		}
+	}
+	return content, nil
+}
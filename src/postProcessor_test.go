@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPostProcess(t *testing.T) {
+	type args struct {
+		names   []string
+		content string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no post-processor",
+			args: args{content: "a  \n"},
+			want: "a  \n",
+		},
+		{
+			name: "trim-trailing-space",
+			args: args{names: []string{"trim-trailing-space"}, content: "a  \nb\t\n"},
+			want: "a\nb\n",
+		},
+		{
+			name: "final-newline",
+			args: args{names: []string{"final-newline"}, content: "a\n\n\n"},
+			want: "a\n",
+		},
+		{
+			name: "squeeze-blank-lines",
+			args: args{names: []string{"squeeze-blank-lines"}, content: "a\n\n  \n\nb\nc"},
+			want: "a\n\nb\nc",
+		},
+		{
+			name: "in order",
+			args: args{names: []string{"trim-trailing-space", "final-newline"}, content: "a \n  "},
+			want: "a\n",
+		},
+		{
+			name:    "unknown",
+			args:    args{names: []string{"unknown"}, content: "a"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := postProcess(tt.args.names, []byte(tt.args.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("postProcess() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("postProcess() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// The name of the project file, searched in the config dir and its parents
+const projectFileName = "examplar.yaml"
+
+// The flags whose paths are relative to the working dir, in the project file they are relative to the project file.
+// The other paths are relative to the config dir, like on the command line.
+var projectRelativeFlags = []string{"config-dir", "template-dir", "template-lib-dir", "output-dir", "cache-dir", "report", "dump-file"}
+
+// ProjectFile provides the defaults of the arguments, keyed by the flag names without the dashes, e.g.
+//
+//	feature-file: [features.txt]
+//	template-dir: templates
+//	profiles:
+//	  staging:
+//	    property-file: [staging.properties]
+//	    feature-set: [one]
+//
+// The list values of a profile are put before the defaults, so the property files of the profile take precedence, the
+// other values replace them.
+type ProjectFile struct {
+	path     string
+	defaults map[string]interface{}
+	profiles map[string]map[string]interface{}
+}
+
+// findProjectFile returns the path of the project file in the dir or the closest parent, "" if there is none.
+func findProjectFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, projectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func readProjectFile(path string) (ProjectFile, error) {
+	project := ProjectFile{path: path, defaults: map[string]interface{}{}, profiles: map[string]map[string]interface{}{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return project, err
+	}
+	var content struct {
+		Defaults map[string]interface{}            `yaml:",inline"`
+		Profiles map[string]map[string]interface{} `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return project, fmt.Errorf("%s: %w", path, err)
+	}
+	if content.Defaults != nil {
+		project.defaults = content.Defaults
+	}
+	if content.Profiles != nil {
+		project.profiles = content.Profiles
+	}
+	return project, nil
+}
+
+// Apply sets the fields of the arguments from the defaults and the profile. Without a profile the one named by the
+// profile key of the project file is used, if any.
+func (project ProjectFile) Apply(args *Args, profile string) error {
+	if profile == "" {
+		if name, ok := project.defaults["profile"].(string); ok {
+			profile = name
+		}
+	}
+	if err := project.apply(args, project.defaults, false); err != nil {
+		return fmt.Errorf("%s: %w", project.path, err)
+	}
+	if profile == "" {
+		return nil
+	}
+	values, ok := project.profiles[profile]
+	if !ok {
+		return fmt.Errorf("%s: unknown profile '%s'", project.path, profile)
+	}
+	if err := project.apply(args, values, true); err != nil {
+		return fmt.Errorf("%s: profile %s: %w", project.path, profile, err)
+	}
+	args.Profile = profile
+	return nil
+}
+
+func (project ProjectFile) apply(args *Args, values map[string]interface{}, extend bool) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	rv := reflect.ValueOf(args).Elem()
+	for _, key := range keys {
+		field, ok := argsField(rv, key)
+		if !ok {
+			return fmt.Errorf("unknown key '%s', use the flag names without the dashes", key)
+		}
+		value := values[key]
+		if slices.Contains(projectRelativeFlags, key) {
+			if path, ok := value.(string); ok && !filepath.IsAbs(path) {
+				value = filepath.Join(filepath.Dir(project.path), path)
+			}
+		}
+		if err := setArgsField(field, value, extend); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// argsField finds the field of the arguments by its flag name.
+func argsField(rv reflect.Value, flag string) (reflect.Value, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		for _, option := range strings.Split(rt.Field(i).Tag.Get("arg"), ",") {
+			if option == "--"+flag {
+				return rv.Field(i), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setArgsField sets a string, an integer, a bool or a string list field, a list field accepts a single string or a map
+// of key=value entries too. With extend the list is put before the current values of the field.
+func setArgsField(field reflect.Value, value interface{}, extend bool) error {
+	switch field.Kind() {
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+		field.SetString(s)
+	case reflect.Int:
+		i, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("%v is not an integer", value)
+		}
+		field.SetInt(int64(i))
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%v is not a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		list, err := toList(value)
+		if m, ok := value.(map[string]interface{}); ok {
+			list, err = keyValueList(m), nil
+		}
+		if err != nil {
+			list = []interface{}{value}
+		}
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("%v is not a string", item)
+			}
+			values = append(values, s)
+		}
+		if extend {
+			values = append(values, field.Interface().([]string)...)
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("%s fields are not supported", field.Type())
+	}
+	return nil
+}
+
+// keyValueList returns the entries of the map as key=value strings in key order.
+func keyValueList(m map[string]interface{}) []interface{} {
+	list := make([]interface{}, 0, len(m))
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		list = append(list, fmt.Sprintf("%s=%v", key, m[key]))
+	}
+	return list
+}
+
+// applyProjectFile sets the defaults of the arguments from the project file found from the config dir, if any.
+func applyProjectFile(args *Args, configDir string, profile string) error {
+	path, err := findProjectFile(configDir)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		if profile != "" {
+			return fmt.Errorf("--profile %s needs a %s in %s or its parents", profile, projectFileName, configDir)
+		}
+		return nil
+	}
+	project, err := readProjectFile(path)
+	if err != nil {
+		return err
+	}
+	return project.Apply(args, profile)
+}
+
+// replaceLists gives the lists of the command line precedence over the ones of the project file. The list flags are
+// parsed one value per flag, appending to the list of the project file rather than replacing it.
+func replaceLists(args *Args, cli Args) {
+	target, source := reflect.ValueOf(args).Elem(), reflect.ValueOf(cli)
+	for i := 0; i < source.NumField(); i++ {
+		if field := source.Field(i); field.Kind() == reflect.Slice && field.Len() > 0 {
+			target.Field(i).Set(field)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindProjectFile(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "configs", "team")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, projectFileName)
+	if err := os.WriteFile(path, []byte("feature-file: features.txt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := findProjectFile(configDir)
+	if err != nil || got != path {
+		t.Errorf("findProjectFile() = %q, %v, want %q", got, err, path)
+	}
+}
+
+func TestProjectFile_Apply(t *testing.T) {
+	project := ProjectFile{
+		path: "/project/examplar.yaml",
+		defaults: map[string]interface{}{
+			"config-dir":           "configs",
+			"feature-file":         "features.txt",
+			"feature-mapping-file": "feature-rename.properties",
+			"property-file":        []interface{}{"one.properties"},
+			"validate-output":      true,
+		},
+		profiles: map[string]map[string]interface{}{
+			"staging": {
+				"property-file": []interface{}{"staging.properties"},
+				"feature-set":   []interface{}{"one"},
+				"template-dir":  "/templates",
+			},
+			"broken": {"feature-sets": "one"},
+		},
+	}
+	tests := []struct {
+		name     string
+		defaults map[string]interface{}
+		profile  string
+		want     Args
+		wantErr  string
+	}{
+		{
+			name: "defaults",
+			want: Args{
+				ConfigDir:          "/project/configs",
+				FeatureFile:        []string{"features.txt"},
+				FeatureMappingFile: "feature-rename.properties",
+				PropertyFiles:      []string{"one.properties"},
+				ValidateOutput:     true,
+			},
+		},
+		{
+			name:    "profile",
+			profile: "staging",
+			want: Args{
+				ConfigDir:          "/project/configs",
+				FeatureFile:        []string{"features.txt"},
+				FeatureMappingFile: "feature-rename.properties",
+				PropertyFiles:      []string{"staging.properties", "one.properties"},
+				FeatureSet:         []string{"one"},
+				TemplateDir:        "/templates",
+				ValidateOutput:     true,
+				Profile:            "staging",
+			},
+		},
+		{
+			name:    "unknown profile",
+			profile: "prod",
+			wantErr: "/project/examplar.yaml: unknown profile 'prod'",
+		},
+		{
+			name:    "unknown key",
+			profile: "broken",
+			wantErr: "/project/examplar.yaml: profile broken: unknown key 'feature-sets', use the flag names without the dashes",
+		},
+		{
+			name: "warning policies as a map",
+			defaults: map[string]interface{}{
+				"warning-policy": map[string]interface{}{"unused-config-key": "ignore", "missing-feature": "error"},
+			},
+			want: Args{WarningPolicy: []string{"missing-feature=error", "unused-config-key=ignore"}},
+		},
+		{
+			name:     "wrong type",
+			defaults: map[string]interface{}{"validate-output": "yes"},
+			wantErr:  "/project/examplar.yaml: validate-output: yes is not a boolean",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := project
+			if tt.defaults != nil {
+				project.defaults = tt.defaults
+			}
+			args := Args{}
+			err := project.Apply(&args, tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Apply() error = %v, wantErr %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("Apply() = %+v, want %+v", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProjectFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), projectFileName)
+	content := "template-dir: templates\nprofiles:\n  staging:\n    feature-set: one\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := readProjectFile(path)
+	if err != nil {
+		t.Fatalf("readProjectFile() error = %v", err)
+	}
+	want := ProjectFile{
+		path:     path,
+		defaults: map[string]interface{}{"template-dir": "templates"},
+		profiles: map[string]map[string]interface{}{"staging": {"feature-set": "one"}},
+	}
+	if !reflect.DeepEqual(project, want) {
+		t.Errorf("readProjectFile() = %+v, want %+v", project, want)
+	}
+}
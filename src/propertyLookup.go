@@ -1,13 +1,20 @@
 package main
 
-import "reflect"
+import (
+	"maps"
+	"reflect"
+	"sync"
+)
 
 type PropertiesLookup struct {
 	properties []interface{}
+	// Optional, counts the lookups of each key
+	usage *PropertyUsage
 }
 
 // HasProperty returns true if the key is found in any of the properties.
 func (config PropertiesLookup) HasProperty(key string) bool {
+	config.usage.record(key)
 	for _, prop := range config.properties {
 		val := reflect.ValueOf(prop).MapIndex(reflect.ValueOf(key))
 		if val.IsValid() {
@@ -19,6 +26,7 @@ func (config PropertiesLookup) HasProperty(key string) bool {
 
 // GetProperty returns the value of the key if found in any of the properties.
 func (config PropertiesLookup) GetProperty(key string) interface{} {
+	config.usage.record(key)
 	for _, prop := range config.properties {
 		val := reflect.ValueOf(prop).MapIndex(reflect.ValueOf(key))
 		if val.IsValid() {
@@ -27,3 +35,43 @@ func (config PropertiesLookup) GetProperty(key string) interface{} {
 	}
 	return nil
 }
+
+// Merged returns all properties in one map, the value of a key is the one GetProperty returns.
+func (config PropertiesLookup) Merged() map[string]interface{} {
+	m := make(map[string]interface{})
+	for i := len(config.properties) - 1; i >= 0; i-- {
+		iter := reflect.ValueOf(config.properties[i]).MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+	}
+	return m
+}
+
+// PropertyUsage counts the lookups of the property keys by the templates, it's safe for concurrent use.
+type PropertyUsage struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (usage *PropertyUsage) record(key string) {
+	if usage == nil {
+		return
+	}
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	if usage.counts == nil {
+		usage.counts = make(map[string]int)
+	}
+	usage.counts[key]++
+}
+
+// Counts returns the number of lookups by key.
+func (usage *PropertyUsage) Counts() map[string]int {
+	if usage == nil {
+		return nil
+	}
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	return maps.Clone(usage.counts)
+}
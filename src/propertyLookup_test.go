@@ -120,3 +120,20 @@ func TestPropertiesLookup_GetProperty(t *testing.T) {
 		})
 	}
 }
+
+func TestPropertiesLookup_Merged(t *testing.T) {
+	config := PropertiesLookup{
+		properties: []interface{}{
+			map[string]string{"key1": "value1", "key2": "value2"},
+			map[string]string{"key2": "bar", "foo": "value foo"},
+		},
+	}
+	want := map[string]interface{}{
+		"key1": "value1",
+		"key2": "value2",
+		"foo":  "value foo",
+	}
+	if got := config.Merged(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merged() = %v, want %v", got, want)
+	}
+}
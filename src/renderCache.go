@@ -0,0 +1,211 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// RenderCache skips the feature sets rendered before from the same inputs. A feature set is fresh when the tool version,
+// the arguments, every input file of the pipeline steps, the property layers and the templates it read have the same
+// hashes, and its output files are unchanged. The cache dir has a manifest per tool version and arguments.
+type RenderCache struct {
+	file     string
+	manifest cacheManifest
+	Hits     int
+	Misses   int
+}
+
+type cacheManifest struct {
+	FeatureSets map[string]cachedFeatureSet `json:"featureSets"`
+}
+
+type cachedFeatureSet struct {
+	// The hash of the inputs of the pipeline steps and the property layers
+	Inputs string `json:"inputs"`
+	// The hashes of the template files read, by path
+	Templates map[string]string `json:"templates"`
+	// The hashes of the output files, by path relative to the output dir
+	Outputs map[string]string `json:"outputs"`
+}
+
+// OpenRenderCache loads the manifest of the arguments from the cache dir, an unreadable manifest is an empty one.
+func OpenRenderCache(args Args) *RenderCache {
+	cache := &RenderCache{
+		file:     filepath.Join(args.CacheDir, hashValues(toolVersion(), cacheArgs(args))[:16]+".json"),
+		manifest: cacheManifest{FeatureSets: map[string]cachedFeatureSet{}},
+	}
+	if data, err := os.ReadFile(cache.file); err == nil {
+		if err := json.Unmarshal(data, &cache.manifest); err != nil || cache.manifest.FeatureSets == nil {
+			cache.manifest = cacheManifest{FeatureSets: map[string]cachedFeatureSet{}}
+		}
+	}
+	return cache
+}
+
+// Fresh tells if the feature set can be skipped and counts the hit or the miss.
+func (cache *RenderCache) Fresh(featureSet string, inputs string, outputDir string) bool {
+	entry, ok := cache.manifest.FeatureSets[featureSet]
+	fresh := ok && entry.Inputs == inputs && unchangedFiles(entry.Templates, "")
+	fresh = fresh && unchangedFiles(entry.Outputs, outputDir)
+	if fresh {
+		cache.Hits++
+	} else {
+		cache.Misses++
+	}
+	return fresh
+}
+
+// Store records the inputs, the templates and the outputs of the rendered feature set.
+func (cache *RenderCache) Store(featureSet string, inputs string, templates []string, outputs []RenderedOutput) {
+	entry := cachedFeatureSet{Inputs: inputs, Templates: hashFiles(templates), Outputs: map[string]string{}}
+	for _, output := range outputs {
+		entry.Outputs[output.Path] = hashBytes(output.Content)
+	}
+	cache.manifest.FeatureSets[featureSet] = entry
+}
+
+// Outputs returns the hashes of the outputs of the feature set by their path relative to the output dir.
+func (cache *RenderCache) Outputs(featureSet string) map[string]string {
+	return cache.manifest.FeatureSets[featureSet].Outputs
+}
+
+// Forget removes the feature set, it's rendered again next time.
+func (cache *RenderCache) Forget(featureSet string) {
+	delete(cache.manifest.FeatureSets, featureSet)
+}
+
+// Save writes the manifest to the cache dir.
+func (cache *RenderCache) Save() error {
+	data, err := json.MarshalIndent(cache.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cache.file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(cache.file, data, 0644)
+}
+
+// cacheInputs returns the hash of the files read by the pipeline steps and of the property layers in order.
+func cacheInputs(files []string, properties []interface{}) string {
+	layers := make([]string, len(properties))
+	for i, layer := range properties {
+		data, err := json.Marshal(layer)
+		if err != nil {
+			panic(err)
+		}
+		layers[i] = hashBytes(data)
+	}
+	return hashValues(hashFiles(files), layers)
+}
+
+// cacheArgs returns the arguments without the ones that don't change the outputs.
+func cacheArgs(args Args) Args {
+	args.CacheDir = ""
+	args.NoCache = false
+	args.Watch = false
+	args.Jobs = 0
+	args.Profile = ""
+	args.Report = ""
+	args.LogLevel = ""
+	args.LogFormat = ""
+	args.DumpAfter = ""
+	args.DumpContext = false
+	args.DumpKeys = nil
+	args.DumpFormat = ""
+	args.DumpFile = ""
+	args.WarningPolicy = nil
+	return args
+}
+
+// unchangedFiles tells if the files still have the hashes, the paths are relative to the dir.
+func unchangedFiles(hashes map[string]string, dir string) bool {
+	for file, hash := range hashes {
+		if hashFile(path.Join(dir, file)) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// hashFiles returns the hashes of the files by path, a missing file has an empty hash so creating it is a change.
+func hashFiles(files []string) map[string]string {
+	hashes := make(map[string]string, len(files))
+	for _, file := range files {
+		hashes[file] = hashFile(file)
+	}
+	return hashes
+}
+
+func hashFile(file string) string {
+	info, err := os.Stat(file)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(file)
+		if err != nil {
+			return ""
+		}
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		return hashValues(names)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return hashBytes(data)
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashValues returns the hash of the values as JSON, the maps are hashed in key order.
+func hashValues(values ...interface{}) string {
+	data, err := json.Marshal(values)
+	if err != nil {
+		panic(err)
+	}
+	return hashBytes(data)
+}
+
+// toolVersion identifies the build, a build from modified or unknown sources is identified by the hash of the
+// executable.
+var toolVersion = sync.OnceValue(func() string {
+	version := runtime.Version()
+	revision, modified := "", false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = fmt.Sprintf("%s %s", version, info.Main.Version)
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+	}
+	if revision != "" && !modified {
+		return version + " " + revision
+	}
+	executable, err := os.Executable()
+	if err == nil {
+		if hash := hashFile(executable); hash != "" {
+			return version + " " + hash
+		}
+	}
+	return version + " unknown"
+})
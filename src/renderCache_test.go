@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderCache(t *testing.T) {
+	dir := t.TempDir()
+	template := filepath.Join(dir, "one.tmpl")
+	outputDir := filepath.Join(dir, "out")
+	write := func(file, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(template, "{{ .featureSet }}")
+	write(filepath.Join(outputDir, "one"), "one")
+	args := Args{CacheDir: filepath.Join(dir, "cache"), OutputDir: outputDir}
+	outputs := []RenderedOutput{{Path: "one", Content: []byte("one")}}
+
+	cache := OpenRenderCache(args)
+	if cache.Fresh("one", "inputs", outputDir) {
+		t.Error("Fresh() of an empty cache = true")
+	}
+	cache.Store("one", "inputs", []string{template}, outputs)
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func()
+		inputs string
+		args   Args
+		want   bool
+	}{
+		{name: "unchanged", inputs: "inputs", args: args, want: true},
+		{name: "inputs changed", inputs: "other", args: args, want: false},
+		{name: "other arguments", inputs: "inputs", args: Args{CacheDir: args.CacheDir, OutputDir: outputDir, FormatOutput: true}, want: false},
+		{name: "cache arguments", inputs: "inputs", args: Args{CacheDir: args.CacheDir, OutputDir: outputDir, Watch: true}, want: true},
+		{name: "output changed", change: func() { write(filepath.Join(outputDir, "one"), "edited") }, inputs: "inputs", args: args, want: false},
+		{name: "template changed", change: func() { write(template, "{{ .features }}") }, inputs: "inputs", args: args, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.change != nil {
+				tt.change()
+			}
+			cache := OpenRenderCache(tt.args)
+			if got := cache.Fresh("one", tt.inputs, outputDir); got != tt.want {
+				t.Errorf("Fresh() = %v, want %v", got, tt.want)
+			}
+			if tt.want && (cache.Hits != 1 || cache.Misses != 0) || !tt.want && (cache.Hits != 0 || cache.Misses != 1) {
+				t.Errorf("Hits = %d, Misses = %d", cache.Hits, cache.Misses)
+			}
+		})
+	}
+}
+
+func TestCacheInputs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "one.properties")
+	if err := os.WriteFile(file, []byte("a: 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layers := []interface{}{map[string]interface{}{"a": "1"}, map[string]interface{}{"b": "2"}}
+	inputs := cacheInputs([]string{file}, layers)
+	if got := cacheInputs([]string{file}, layers); got != inputs {
+		t.Errorf("cacheInputs() = %s, want %s", got, inputs)
+	}
+	if got := cacheInputs([]string{file}, []interface{}{layers[1], layers[0]}); got == inputs {
+		t.Error("cacheInputs() of the swapped layers is unchanged")
+	}
+	if err := os.WriteFile(file, []byte("a: 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := cacheInputs([]string{file}, layers); got == inputs {
+		t.Error("cacheInputs() of the changed file is unchanged")
+	}
+}
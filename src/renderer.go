@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// The delimiters of the front-matter block, a template comment so the template parses with or without it:
+//
+//	{{/*---
+//	output: "{{ .featureSet }}/deployment.yaml"
+//	---*/}}
+const (
+	frontMatterStart = "{{/*---"
+	frontMatterEnd   = "---*/}}"
+)
+
+// FrontMatter holds the settings of a template from its front-matter block.
+type FrontMatter struct {
+	// The output path pattern, a template rendered with .featureSet and .template
+	Output string `yaml:"output"`
+	// The template engine, html (default) or text
+	Engine string `yaml:"engine"`
+	// The file mode of the output file, e.g. "0600"
+	Mode FileMode `yaml:"mode"`
+	// The properties that must be defined to render the template
+	Required StringList `yaml:"required"`
+	// Only the features whose values equal all of these are rendered, a list value matches if it contains the value
+	Filter map[string]interface{} `yaml:"filter"`
+	// The post-processors applied to the output in order
+	PostProcess StringList `yaml:"post-process"`
+	// How the template is rendered, once per feature set (per-set, default) or once per feature (per-feature)
+	Render string `yaml:"render"`
+	// The format the output is validated as: auto (by the output extension), yaml, json, properties or none
+	Validate string `yaml:"validate"`
+	// Re-format the validated output canonically, validates by the output extension if validate is not set
+	Format bool `yaml:"format"`
+}
+
+// StringList is a list of strings that may be written as a single string in YAML or JSON.
+type StringList []string
+
+func (list *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*list = StringList{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*list = values
+	return nil
+}
+
+func (list *StringList) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*list = StringList{value}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*list = values
+	return nil
+}
+
+// FileMode is a file mode written in octal in YAML, e.g. "0644". YAML would read 0644 as decimal otherwise.
+type FileMode fs.FileMode
+
+func (mode *FileMode) UnmarshalYAML(node *yaml.Node) error {
+	v, err := strconv.ParseUint(strings.TrimPrefix(node.Value, "0o"), 8, 32)
+	if err != nil || v > 0777 {
+		return fmt.Errorf("line %d: invalid file mode '%s'", node.Line, node.Value)
+	}
+	*mode = FileMode(v)
+	return nil
+}
+
+// splitFrontMatter removes the front-matter block from the template content. The block is replaced by an empty
+// template comment of the same lines, so the line numbers of template errors still match the file.
+func splitFrontMatter(file string, content []byte) (FrontMatter, []byte, error) {
+	frontMatter := FrontMatter{}
+	text := string(content)
+	if !strings.HasPrefix(text, frontMatterStart+"\n") {
+		return frontMatter, content, nil
+	}
+	end := strings.Index(text, "\n"+frontMatterEnd)
+	if end < 0 {
+		return frontMatter, nil, fmt.Errorf("%s: front-matter is not closed by %s", file, frontMatterEnd)
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(text[len(frontMatterStart):end]))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&frontMatter); err != nil && !errors.Is(err, io.EOF) {
+		return frontMatter, nil, fmt.Errorf("%s: front-matter: %w", file, err)
+	}
+	// the new line after the block belongs to the block
+	headerEnd := end + 1 + len(frontMatterEnd)
+	if strings.HasPrefix(text[headerEnd:], "\n") {
+		headerEnd++
+	}
+	lines := strings.Count(text[:headerEnd], "\n")
+	return frontMatter, []byte("{{/*" + strings.Repeat("\n", lines) + "*/}}" + text[headerEnd:]), nil
+}
+
+// featureSetTemplates returns the template files of the feature set, either every template in the <featureSet>
+// dir, partials excluded, or the single <featureSet>.tmpl.
+func featureSetTemplates(templates fs.FS, featureSet string) ([]string, error) {
+	if info, err := fs.Stat(templates, featureSet); err == nil && info.IsDir() {
+		files, err := fs.Glob(templates, path.Join(featureSet, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		files = slices.DeleteFunc(files, func(f string) bool {
+			matched, _ := path.Match(partialPattern, path.Base(f))
+			return matched
+		})
+		if len(files) == 0 {
+			return nil, fmt.Errorf("No template found in %s", featureSet)
+		}
+		return files, nil
+	}
+	file := fmt.Sprintf("%s.tmpl", featureSet)
+	if _, err := fs.Stat(templates, file); err != nil {
+		return nil, fmt.Errorf("No template found for feature set '%s': %w", featureSet, err)
+	}
+	return []string{file}, nil
+}
+
+// outputPath decides the path of the rendered template relative to the output dir. Without an output pattern in the
+// front-matter, it's the template path without .tmpl, e.g. one/deployment.yaml.tmpl is rendered to one/deployment.yaml
+// The pattern is rendered with the data of the template and .template, the template file name without .tmpl, its
+// functions look up the properties of the run like the ones of the template.
+func outputPath(file string, frontMatter FrontMatter, data map[string]interface{}, lookup PropertiesLookup) (string, error) {
+	name := strings.TrimSuffix(file, ".tmpl")
+	if frontMatter.Output == "" {
+		return name, nil
+	}
+	tmpl, err := template.New(file + ":output").Funcs(functionMap(templateFunctions(lookup))).Parse(frontMatter.Output)
+	if err != nil {
+		return "", err
+	}
+	patternData := map[string]interface{}{
+		"template": path.Base(name),
+	}
+	for k, v := range data {
+		patternData[k] = v
+	}
+	buffer := bytes.Buffer{}
+	if err := tmpl.Execute(&buffer, patternData); err != nil {
+		return "", err
+	}
+	p := path.Clean(buffer.String())
+	if path.IsAbs(p) || strings.HasPrefix(p, "../") || p == ".." {
+		return "", fmt.Errorf("%s: output '%s' is outside of the output dir", file, p)
+	}
+	return p, nil
+}
+
+// filterFeatures keeps the features matching the filter of the front-matter.
+func filterFeatures(features interface{}, filter map[string]interface{}) (interface{}, error) {
+	if len(filter) == 0 {
+		return features, nil
+	}
+	step := ListFilterTransformer{
+		predicate: func(input interface{}) bool {
+			for key, value := range filter {
+				if !MapValuePredicate(key, value)(input) && !MapValueContainsPredicate(key, value)(input) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+	return step.Transform(features)
+}
+
+// missingProperties returns the required properties that are not defined.
+func missingProperties(required []string, lookup PropertiesLookup) []string {
+	missing := make([]string, 0)
+	for _, key := range required {
+		if !lookup.HasProperty(key) {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// renderTemplate renders the template with the data of the feature set. With render: per-feature the template is
+// rendered once for each feature, with .feature and .index added to the data.
+func renderTemplate(file string, tmpl Executable, frontMatter FrontMatter, data map[string]interface{}, lookup PropertiesLookup) ([]RenderedOutput, error) {
+	features, err := filterFeatures(data["features"], frontMatter.Filter)
+	if err != nil {
+		return nil, err
+	}
+	setData := make(map[string]interface{})
+	for k, v := range data {
+		setData[k] = v
+	}
+	setData["features"] = features
+
+	switch frontMatter.Render {
+	case "", "per-set":
+		output, err := renderOutput(file, tmpl, frontMatter, setData, lookup)
+		if err != nil {
+			return nil, err
+		}
+		return []RenderedOutput{output}, nil
+	case "per-feature":
+		if frontMatter.Output == "" {
+			return nil, fmt.Errorf("%s: render: per-feature needs an output pattern", file)
+		}
+		list, err := toList(features)
+		if err != nil {
+			return nil, err
+		}
+		outputs := make([]RenderedOutput, 0, len(list))
+		paths := make(map[string]int)
+		for i, feature := range list {
+			featureData := make(map[string]interface{})
+			for k, v := range setData {
+				featureData[k] = v
+			}
+			featureData["feature"] = feature
+			featureData["index"] = i
+			output, err := renderOutput(file, tmpl, frontMatter, featureData, lookup)
+			if err != nil {
+				return nil, err
+			}
+			if j, ok := paths[output.Path]; ok {
+				return nil, fmt.Errorf("%s: features %d and %d are both rendered to %s", file, j, i, output.Path)
+			}
+			paths[output.Path] = i
+			outputs = append(outputs, output)
+		}
+		return outputs, nil
+	}
+	return nil, fmt.Errorf("%s: unknown render mode '%s'", file, frontMatter.Render)
+}
+
+// renderOutput executes the template once, post-processes the result and validates it in the format of the output.
+func renderOutput(file string, tmpl Executable, frontMatter FrontMatter, data map[string]interface{}, lookup PropertiesLookup) (RenderedOutput, error) {
+	buffer := bytes.Buffer{}
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return RenderedOutput{}, err
+	}
+	content, err := postProcess(frontMatter.PostProcess, buffer.Bytes())
+	if err != nil {
+		return RenderedOutput{}, fmt.Errorf("%s: %w", file, err)
+	}
+	p, err := outputPath(file, frontMatter, data, lookup)
+	if err != nil {
+		return RenderedOutput{}, err
+	}
+	validate := frontMatter.Validate
+	if validate == "" && frontMatter.Format {
+		validate = "auto"
+	}
+	format, err := outputFormat(p, validate)
+	if err != nil {
+		return RenderedOutput{}, fmt.Errorf("%s: %w", file, err)
+	}
+	if format != "" {
+		if line, err := validateOutput(format, content); err != nil {
+			return RenderedOutput{}, &OutputValidationError{
+				Path: p, Format: format, Line: line, Message: err.Error(), TemplateFile: file,
+				content: content,
+			}
+		}
+		if frontMatter.Format {
+			if content, err = formatOutput(format, content); err != nil {
+				return RenderedOutput{}, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+	return RenderedOutput{Path: p, Template: file, Content: content, Mode: fs.FileMode(frontMatter.Mode)}, nil
+}
+
+// RenderedOutput is the result of rendering one template.
+type RenderedOutput struct {
+	// The path relative to the output dir
+	Path     string
+	Template string
+	Content  []byte
+	// The file mode of the output file, 0644 if zero
+	Mode fs.FileMode
+}
+
+// writeOutput writes the rendered output below the output dir, or prints it to w when there is no output dir. A file
+// with the same content is not rewritten, false is returned for it.
+func writeOutput(w io.Writer, outputDir string, output RenderedOutput) (bool, error) {
+	if outputDir == "" {
+		fmt.Fprintf(w, "== BEGIN OUTPUT %s ==\n%s", output.Path, output.Content)
+		return true, nil
+	}
+	file := path.Join(outputDir, output.Path)
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return false, err
+	}
+	mode := output.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	changed := true
+	if current, err := os.ReadFile(file); err == nil && bytes.Equal(current, output.Content) {
+		changed = false
+	} else if err := os.WriteFile(file, output.Content, mode); err != nil {
+		return false, err
+	}
+	// WriteFile only applies the mode to new files
+	return changed, os.Chmod(file, mode)
+}
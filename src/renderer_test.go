@@ -0,0 +1,377 @@
+package main
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	type args struct {
+		content string
+	}
+	tests := []struct {
+		name            string
+		args            args
+		wantFrontMatter FrontMatter
+		wantContent     string
+		wantErr         bool
+	}{
+		{
+			name:        "no front-matter",
+			args:        args{content: "---\nName: foo\n"},
+			wantContent: "---\nName: foo\n",
+		},
+		{
+			name:            "front-matter",
+			args:            args{content: "{{/*---\noutput: foo.yaml\n---*/}}\nName: foo\n"},
+			wantFrontMatter: FrontMatter{Output: "foo.yaml"},
+			wantContent:     "{{/*\n\n\n*/}}Name: foo\n",
+		},
+		{
+			name:        "empty front-matter",
+			args:        args{content: "{{/*---\n---*/}}\nName: foo"},
+			wantContent: "{{/*\n\n*/}}Name: foo",
+		},
+		{
+			name: "all settings",
+			args: args{content: "{{/*---\noutput: foo.yaml\nengine: html\nmode: 0600\nrequired: config_name\n" +
+				"filter:\n  in: A\npost-process: [trim-trailing-space, final-newline]\n---*/}}\nName: foo\n"},
+			wantFrontMatter: FrontMatter{
+				Output:      "foo.yaml",
+				Engine:      "html",
+				Mode:        0600,
+				Required:    StringList{"config_name"},
+				Filter:      map[string]interface{}{"in": "A"},
+				PostProcess: StringList{"trim-trailing-space", "final-newline"},
+			},
+			wantContent: "{{/*\n\n\n\n\n\n\n\n\n*/}}Name: foo\n",
+		},
+		{
+			name:    "invalid mode",
+			args:    args{content: "{{/*---\nmode: rw\n---*/}}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "not closed",
+			args:    args{content: "{{/*---\noutput: foo.yaml\nName: foo\n"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			args:    args{content: "{{/*---\noutptu: foo.yaml\n---*/}}\n"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotFrontMatter, gotContent, err := splitFrontMatter("file.tmpl", []byte(tt.args.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitFrontMatter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(gotFrontMatter, tt.wantFrontMatter) {
+				t.Errorf("splitFrontMatter() gotFrontMatter = %v, want %v", gotFrontMatter, tt.wantFrontMatter)
+			}
+			if string(gotContent) != tt.wantContent {
+				t.Errorf("splitFrontMatter() gotContent = %q, want %q", gotContent, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestFeatureSetTemplates(t *testing.T) {
+	type args struct {
+		templates  fs.FS
+		featureSet string
+	}
+	templates := fstest.MapFS{
+		"one.tmpl":                 {Data: []byte("one")},
+		"two/deployment.yaml.tmpl": {Data: []byte("deployment")},
+		"two/README.md.tmpl":       {Data: []byte("readme")},
+		"two/_partial.tmpl":        {Data: []byte("partial")},
+		"three/_partial.tmpl":      {Data: []byte("partial")},
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "single template",
+			args: args{templates: templates, featureSet: "one"},
+			want: []string{"one.tmpl"},
+		},
+		{
+			name: "feature set dir",
+			args: args{templates: templates, featureSet: "two"},
+			want: []string{"two/README.md.tmpl", "two/deployment.yaml.tmpl"},
+		},
+		{
+			name:    "only partials",
+			args:    args{templates: templates, featureSet: "three"},
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			args:    args{templates: templates, featureSet: "four"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := featureSetTemplates(tt.args.templates, tt.args.featureSet)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("featureSetTemplates() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("featureSetTemplates() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	type args struct {
+		file        string
+		frontMatter FrontMatter
+	}
+	data := map[string]interface{}{
+		"featureSet": "one",
+	}
+	lookup := PropertiesLookup{properties: []interface{}{map[string]interface{}{"env": "prod"}}}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "template name",
+			args: args{file: "one/deployment.yaml.tmpl"},
+			want: "one/deployment.yaml",
+		},
+		{
+			name: "output pattern",
+			args: args{
+				file:        "one/deployment.yaml.tmpl",
+				frontMatter: FrontMatter{Output: "k8s/{{ .featureSet | upper }}-{{ .template }}"},
+			},
+			want: "k8s/ONE-deployment.yaml",
+		},
+		{
+			name: "property in the output pattern",
+			args: args{
+				file:        "one/deployment.yaml.tmpl",
+				frontMatter: FrontMatter{Output: `{{ getProperty "env" }}/{{ .template }}`},
+			},
+			want: "prod/deployment.yaml",
+		},
+		{
+			name: "outside of the output dir",
+			args: args{
+				file:        "one.tmpl",
+				frontMatter: FrontMatter{Output: "../{{ .featureSet }}"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := outputPath(tt.args.file, tt.args.frontMatter, data, lookup)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("outputPath() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("outputPath() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterFeatures(t *testing.T) {
+	features := []interface{}{
+		map[string]interface{}{"Name": "Foo", "in": "A", "feature-set": []interface{}{"one", "two"}},
+		map[string]interface{}{"Name": "Bar", "in": "B", "feature-set": "one"},
+	}
+	tests := []struct {
+		name   string
+		filter map[string]interface{}
+		want   interface{}
+	}{
+		{
+			name: "no filter",
+			want: features,
+		},
+		{
+			name:   "equal value",
+			filter: map[string]interface{}{"in": "B"},
+			want:   []interface{}{features[1]},
+		},
+		{
+			name:   "list contains value",
+			filter: map[string]interface{}{"feature-set": "two"},
+			want:   []interface{}{features[0]},
+		},
+		{
+			name:   "all must match",
+			filter: map[string]interface{}{"feature-set": "one", "in": "A"},
+			want:   []interface{}{features[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterFeatures(features, tt.filter)
+			if err != nil {
+				t.Errorf("filterFeatures() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterFeatures() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingProperties(t *testing.T) {
+	lookup := PropertiesLookup{
+		properties: []interface{}{
+			map[string]string{"key1": "value1"},
+		},
+	}
+	got := missingProperties([]string{"key1", "key2"}, lookup)
+	if !reflect.DeepEqual(got, []string{"key2"}) {
+		t.Errorf("missingProperties() got = %v, want %v", got, []string{"key2"})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	type args struct {
+		template    string
+		frontMatter FrontMatter
+	}
+	data := map[string]interface{}{
+		"featureSet": "one",
+		"properties": map[string]interface{}{"config_name": "cfg"},
+		"features": []interface{}{
+			map[string]interface{}{"Name": "Foo", "in": "A"},
+			map[string]interface{}{"Name": "Bar", "in": "B"},
+		},
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []RenderedOutput
+		wantErr bool
+	}{
+		{
+			name: "per set",
+			args: args{
+				template:    `{{ .properties.config_name }}:{{ range .features }} {{ .Name }}{{ end }}`,
+				frontMatter: FrontMatter{Mode: 0600},
+			},
+			want: []RenderedOutput{
+				{Path: "one/list.txt", Template: "one/list.txt.tmpl", Content: []byte("cfg: Foo Bar"), Mode: 0600},
+			},
+		},
+		{
+			name: "per feature",
+			args: args{
+				template: `{{ .index }}/{{ len .features }} {{ .feature.Name }} in {{ .featureSet }}`,
+				frontMatter: FrontMatter{
+					Render: "per-feature",
+					Output: "{{ .featureSet }}/{{ .feature.Name | lower }}.txt",
+				},
+			},
+			want: []RenderedOutput{
+				{Path: "one/foo.txt", Template: "one/list.txt.tmpl", Content: []byte("0/2 Foo in one")},
+				{Path: "one/bar.txt", Template: "one/list.txt.tmpl", Content: []byte("1/2 Bar in one")},
+			},
+		},
+		{
+			name: "per feature, filtered",
+			args: args{
+				template: `{{ .feature.Name }}`,
+				frontMatter: FrontMatter{
+					Render: "per-feature",
+					Output: "{{ .feature.Name }}",
+					Filter: map[string]interface{}{"in": "B"},
+				},
+			},
+			want: []RenderedOutput{
+				{Path: "Bar", Template: "one/list.txt.tmpl", Content: []byte("Bar")},
+			},
+		},
+		{
+			name: "per feature without output",
+			args: args{
+				template:    `{{ .feature.Name }}`,
+				frontMatter: FrontMatter{Render: "per-feature"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "per feature, same output",
+			args: args{
+				template:    `{{ .feature.Name }}`,
+				frontMatter: FrontMatter{Render: "per-feature", Output: "same.txt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "validated and formatted",
+			args: args{
+				template:    `{"features": [{{ range $i, $f := .features }}{{ if $i }}, {{ end }}"{{ $f.Name }}"{{ end }}]}`,
+				frontMatter: FrontMatter{Output: "list.json", Validate: "auto", Format: true},
+			},
+			want: []RenderedOutput{
+				{Path: "list.json", Template: "one/list.txt.tmpl", Content: []byte("{\n  \"features\": [\n    \"Foo\",\n    \"Bar\"\n  ]\n}\n")},
+			},
+		},
+		{
+			name: "invalid output",
+			args: args{
+				template:    `features:{{ range .features }}\n - {{ .Name }}\n  in: {{ .in }}{{ end }}`,
+				frontMatter: FrontMatter{Validate: "yaml"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown output format",
+			args: args{
+				template:    ``,
+				frontMatter: FrontMatter{Validate: "xml"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown render mode",
+			args: args{
+				template:    ``,
+				frontMatter: FrontMatter{Render: "per-line"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("one/list.txt.tmpl").Funcs(functionMap(templateFunctions(PropertiesLookup{}))).Parse(tt.args.template))
+			got, err := renderTemplate("one/list.txt.tmpl", tmpl, tt.args.frontMatter, data, PropertiesLookup{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("renderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderTemplate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
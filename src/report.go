@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RunReport is the summary of a run written by --report: the steps with their timing and counts, the feature sets
+// rendered, the warnings, the files produced and the use of the properties. It's safe for concurrent use. A nil report
+// records nothing.
+type RunReport struct {
+	mu          sync.Mutex
+	Steps       []StepReport       `json:"steps"`
+	FeatureSets []FeatureSetReport `json:"featureSets"`
+	Warnings    []Warning          `json:"warnings"`
+	Files       []FileReport       `json:"files"`
+	Properties  []PropertyReport   `json:"properties"`
+	// The error of the run, empty if it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+type StepReport struct {
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"durationMs"`
+	// The number of entries read from and stored in the context, omitted for the steps without
+	Inputs  *int   `json:"inputs,omitempty"`
+	Outputs *int   `json:"outputs,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type FeatureSetReport struct {
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"durationMs"`
+	Features   int     `json:"features"`
+	Outputs    int     `json:"outputs"`
+	// True if the render cache skipped the feature set
+	Cached bool   `json:"cached,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type FileReport struct {
+	// The path of the file, relative to the output dir if printed
+	Path       string `json:"path"`
+	FeatureSet string `json:"featureSet"`
+	SHA256     string `json:"sha256"`
+	// False if the file already had the content
+	Written bool `json:"written"`
+}
+
+type PropertyReport struct {
+	Key string `json:"key"`
+	// The property file of the value, empty if the key is not defined
+	File    string `json:"file,omitempty"`
+	Defined bool   `json:"defined"`
+	// The number of lookups by the templates
+	Uses int `json:"uses"`
+}
+
+func NewRunReport() *RunReport {
+	return &RunReport{
+		Steps:       []StepReport{},
+		FeatureSets: []FeatureSetReport{},
+		Files:       []FileReport{},
+	}
+}
+
+// contextReport returns the report of the run, nil when no report is written.
+func contextReport(context map[string]interface{}) *RunReport {
+	report, _ := context["report"].(*RunReport)
+	return report
+}
+
+func (report *RunReport) addStep(step pipelineStep, context map[string]interface{}, duration time.Duration, err error) {
+	if report == nil {
+		return
+	}
+	entry := StepReport{
+		Name:       step.name,
+		DurationMs: milliseconds(duration),
+		Inputs:     contextCount(context, step.input),
+		Outputs:    contextCount(context, step.output),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.Steps = append(report.Steps, entry)
+}
+
+func (report *RunReport) addFeatureSet(entry FeatureSetReport) {
+	if report == nil {
+		return
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.FeatureSets = append(report.FeatureSets, entry)
+}
+
+func (report *RunReport) addFile(featureSet string, outputDir string, output RenderedOutput, written bool) {
+	report.addFileHash(featureSet, outputDir, output.Path, hashBytes(output.Content), written)
+}
+
+// addFileHash records an output file by the hash of its content, e.g. one left as it is by the render cache.
+func (report *RunReport) addFileHash(featureSet string, outputDir string, file string, hash string, written bool) {
+	if report == nil {
+		return
+	}
+	if outputDir != "" {
+		file = path.Join(outputDir, file)
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.Files = append(report.Files, FileReport{
+		Path:       file,
+		FeatureSet: featureSet,
+		SHA256:     hash,
+		Written:    written,
+	})
+}
+
+// Write completes the report with the warnings and the property usage of the context and the error of the run, and
+// writes it as JSON to the file.
+func (report *RunReport) Write(file string, context map[string]interface{}, err error) error {
+	if report == nil {
+		return nil
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.Warnings = append([]Warning{}, contextWarnings(context).List()...)
+	report.Properties = propertyReports(context)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// propertyReports returns the properties defined or looked up, by key. The file of a key is the first property file
+// defining it, as GetProperty.
+func propertyReports(context map[string]interface{}) []PropertyReport {
+	properties, ok := context["properties"].([]interface{})
+	if !ok {
+		return []PropertyReport{}
+	}
+	files := context["args"].(Args).PropertyFiles
+	usage, _ := context["property-usage"].(*PropertyUsage)
+	uses := usage.Counts()
+	byKey := make(map[string]PropertyReport)
+	for i := len(properties) - 1; i >= 0; i-- {
+		iter := reflect.ValueOf(properties[i]).MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			byKey[key] = PropertyReport{Key: key, File: files[i], Defined: true}
+		}
+	}
+	for key := range uses {
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = PropertyReport{Key: key}
+		}
+	}
+	reports := make([]PropertyReport, 0, len(byKey))
+	for _, key := range slices.Sorted(maps.Keys(byKey)) {
+		entry := byKey[key]
+		entry.Uses = uses[key]
+		reports = append(reports, entry)
+	}
+	return reports
+}
+
+// contextCount returns the number of entries of the list or map stored under the key, nil if there is none.
+func contextCount(context map[string]interface{}, key string) *int {
+	if key == "" || context[key] == nil {
+		return nil
+	}
+	value := reflect.ValueOf(context[key])
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		count := value.Len()
+		return &count
+	}
+	return nil
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunReport_Write(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.json")
+	context := map[string]interface{}{
+		"args": Args{PropertyFiles: []string{"local.properties", "default.properties"}},
+		"properties": []interface{}{
+			map[string]interface{}{"a": "1"},
+			map[string]interface{}{"a": "2", "b": "3"},
+		},
+		"raw-features":   []string{"foo", "bar"},
+		"features":       []interface{}{"Foo"},
+		"warnings":       &Warnings{},
+		"property-usage": &PropertyUsage{},
+	}
+	report := NewRunReport()
+	context["report"] = report
+	lookup := propertiesLookup(context)
+	lookup.GetProperty("a")
+	lookup.GetProperty("a")
+	lookup.HasProperty("missing")
+	contextWarnings(context).Warnf(WarnUnusedConfigKey, "Config key '%s' not used", "Baz")
+	contextReport(context).addStep(pipelineStep{name: "transform", input: "raw-features", output: "features"}, context, 1500*time.Microsecond, nil)
+	contextReport(context).addStep(pipelineStep{name: "templates"}, context, 0, nil)
+	contextReport(context).addFile("one", "out", RenderedOutput{Path: "one.txt", Content: []byte("one")}, true)
+
+	if err := report.Write(file, context, errors.New("feature set two: failed")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	want := map[string]interface{}{
+		"steps": []interface{}{
+			map[string]interface{}{"name": "transform", "durationMs": 1.5, "inputs": 2.0, "outputs": 1.0},
+			map[string]interface{}{"name": "templates", "durationMs": 0.0},
+		},
+		"featureSets": []interface{}{},
+		"warnings": []interface{}{
+			map[string]interface{}{"type": "unused-config-key", "message": "Config key 'Baz' not used"},
+		},
+		"files": []interface{}{
+			map[string]interface{}{"path": "out/one.txt", "featureSet": "one", "sha256": hashBytes([]byte("one")), "written": true},
+		},
+		"properties": []interface{}{
+			map[string]interface{}{"key": "a", "file": "local.properties", "defined": true, "uses": 2.0},
+			map[string]interface{}{"key": "b", "file": "default.properties", "defined": true, "uses": 0.0},
+			map[string]interface{}{"key": "missing", "defined": false, "uses": 1.0},
+		},
+		"error": "feature set two: failed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %s", data)
+	}
+}
+
+func TestRunReport_Nil(t *testing.T) {
+	var report *RunReport
+	report.addStep(pipelineStep{name: "config"}, nil, 0, nil)
+	report.addFeatureSet(FeatureSetReport{Name: "one"})
+	report.addFile("one", "", RenderedOutput{Path: "one"}, true)
+	if err := report.Write("", nil, nil); err != nil {
+		t.Errorf("Write() error = %v", err)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	var none *Warnings
+	none.Warnf(WarnNoTemplate, "not collected")
+	if list := none.List(); list != nil {
+		t.Errorf("nil Warnings.List() = %v", list)
+	}
+	var logs bytes.Buffer
+	warnings := NewWarnings(slog.New(withoutTimeHandler{slog.NewTextHandler(&logs, nil)}), nil)
+	warnings.Warnf(WarnMissingFeature, "Feature '%s' not found", "Foo")
+	warnings.Warnf(WarnRecordLength, "short record")
+	wantLogs := "level=WARN msg=\"Feature 'Foo' not found\" type=missing-feature\n" +
+		"level=WARN msg=\"short record\" type=record-length\n"
+	if logs.String() != wantLogs {
+		t.Errorf("logs = %q, want %q", logs.String(), wantLogs)
+	}
+	want := []Warning{
+		{Type: WarnMissingFeature, Message: "Feature 'Foo' not found"},
+		{Type: WarnRecordLength, Message: "short record"},
+	}
+	if got := warnings.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+}
+
+func TestWarnings_Policies(t *testing.T) {
+	policies, err := ParseWarningPolicies([]string{"all=error", "unused-config-key = ignore", "missing-feature=warn"})
+	if err != nil {
+		t.Fatalf("ParseWarningPolicies() error = %v", err)
+	}
+	warnings := NewWarnings(slog.New(slog.NewTextHandler(io.Discard, nil)), policies)
+	warnings.Warnf(WarnUnusedConfigKey, "Config key 'Baz' not used")
+	warnings.Warnf(WarnMissingFeature, "Input key 'Fooo' not found")
+	if err := warnings.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+	warnings.Warnf(WarnUnusedMappingKey, "Mapping key 'old' not used")
+	want := []Warning{
+		{Type: WarnMissingFeature, Message: "Input key 'Fooo' not found"},
+		{Type: WarnUnusedMappingKey, Message: "Mapping key 'old' not used", Error: true},
+	}
+	if got := warnings.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+	if err := warnings.Err(); err == nil || err.Error() != "unused-mapping-key: Mapping key 'old' not used" {
+		t.Errorf("Err() = %v", err)
+	}
+}
+
+func TestParseWarningPolicies_Errors(t *testing.T) {
+	tests := []struct {
+		entries []string
+		wantErr string
+	}{
+		{[]string{"missing-feature"}, "warning policy 'missing-feature' is not type=policy"},
+		{[]string{"missing-features=error"}, "unknown warning type 'missing-features', did you mean 'missing-feature'?"},
+		{[]string{"missing-feature=fail"}, "unknown warning policy 'fail', use ignore, warn or error"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseWarningPolicies(tt.entries); err == nil || err.Error() != tt.wantErr {
+			t.Errorf("ParseWarningPolicies(%v) error = %v, want %s", tt.entries, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The bundled schema of config.yaml
+//
+//go:embed schemas/config.schema.json
+var defaultConfigSchema []byte
+
+// Schema is the subset of JSON Schema (draft 7) used to validate the YAML inputs: type, enum, const, properties,
+// patternProperties, additionalProperties, required, items, minItems, maxItems, minLength, maxLength, pattern,
+// minimum, maximum, anyOf, oneOf, allOf, not and $ref to the definitions of the same schema. Other keywords are
+// rejected by loadSchema. true and false are schemas too, they accept everything or nothing.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Definitions          map[string]*Schema `json:"definitions"`
+	Defs                 map[string]*Schema `json:"$defs"`
+	Description          string             `json:"description"`
+	Type                 StringList         `json:"type"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+	Properties           map[string]*Schema `json:"properties"`
+	PatternProperties    map[string]*Schema `json:"patternProperties"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AllOf                []*Schema          `json:"allOf"`
+	Not                  *Schema            `json:"not"`
+	// The false schema
+	never bool
+}
+
+func (schema *Schema) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "true":
+		*schema = Schema{}
+		return nil
+	case "false":
+		*schema = Schema{never: true}
+		return nil
+	}
+	type plain Schema
+	return json.Unmarshal(data, (*plain)(schema))
+}
+
+// SchemaError is a value of a YAML document that doesn't match the schema.
+type SchemaError struct {
+	File string
+	// The YAML path of the value, e.g. Foo.parameters[0].name
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	location := e.File
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, e.Path, e.Message)
+}
+
+// SchemaErrors are all the errors of a validated document.
+type SchemaErrors []SchemaError
+
+func (errors SchemaErrors) Error() string {
+	messages := make([]string, 0, len(errors))
+	for _, e := range errors {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// loadSchema reads the schema and merges the overrides into it, in order. The schema and the overrides may be JSON or
+// YAML. An override is merged like a JSON merge patch (RFC 7386): objects are merged, other values are replaced, and
+// null removes the value, e.g. {"definitions": {"feature": {"additionalProperties": true}}} allows any feature key.
+func loadSchema(schema []byte, overrides ...[]byte) (*Schema, error) {
+	var merged interface{}
+	if err := yaml.Unmarshal(schema, &merged); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	for i, override := range overrides {
+		var patch interface{}
+		if err := yaml.Unmarshal(override, &patch); err != nil {
+			return nil, fmt.Errorf("schema override %d: %w", i+1, err)
+		}
+		merged = mergePatch(merged, patch)
+	}
+	if err := checkKeywords(merged, "#"); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	result := &Schema{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	return result, nil
+}
+
+// The keywords of Schema, and the annotations that don't validate anything
+var schemaKeywords = []string{
+	"$ref", "definitions", "$defs", "description", "type", "enum", "const", "properties", "patternProperties",
+	"additionalProperties", "required", "items", "minItems", "maxItems", "minLength", "maxLength", "pattern", "minimum",
+	"maximum", "anyOf", "oneOf", "allOf", "not", "$schema", "$id", "$comment", "title", "default", "examples",
+}
+
+// checkKeywords returns an error for the first keyword of the schema, or of its sub-schemas, that Schema doesn't
+// support, a schema using it would validate less than it says.
+func checkKeywords(schema interface{}, path string) error {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		if _, ok := schema.(bool); !ok {
+			return fmt.Errorf("%s: a schema is an object or a boolean", path)
+		}
+		return nil
+	}
+	for _, keyword := range slices.Sorted(maps.Keys(object)) {
+		if !slices.Contains(schemaKeywords, keyword) {
+			return fmt.Errorf("%s: unsupported keyword '%s'%s", path, keyword, didYouMean(keyword, schemaKeywords))
+		}
+		value, keywordPath := object[keyword], path+"/"+keyword
+		var err error
+		switch keyword {
+		case "definitions", "$defs", "properties", "patternProperties":
+			schemas, _ := value.(map[string]interface{})
+			for _, name := range slices.Sorted(maps.Keys(schemas)) {
+				if err = checkKeywords(schemas[name], keywordPath+"/"+name); err != nil {
+					break
+				}
+			}
+		case "additionalProperties", "items", "not":
+			err = checkKeywords(value, keywordPath)
+		case "anyOf", "oneOf", "allOf":
+			schemas, _ := value.([]interface{})
+			for i, sub := range schemas {
+				if err = checkKeywords(sub, fmt.Sprintf("%s/%d", keywordPath, i)); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergePatch applies a JSON merge patch to the target.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{})
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+		} else {
+			targetMap[k] = mergePatch(targetMap[k], v)
+		}
+	}
+	return targetMap
+}
+
+// Validate checks the YAML node against the schema and returns every mismatch, sorted by line.
+func (schema *Schema) Validate(file string, node *yaml.Node) SchemaErrors {
+	v := schemaValidation{root: schema, file: file, errors: make(SchemaErrors, 0)}
+	if node.Kind == 0 || node.Kind == yaml.DocumentNode {
+		// an empty document is valid
+		if len(node.Content) == 0 {
+			return v.errors
+		}
+		node = node.Content[0]
+	}
+	v.validate(schema, node, "")
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		return v.errors[i].Line < v.errors[j].Line
+	})
+	return v.errors
+}
+
+// ValidateValue checks a decoded value against the schema, the errors have no line.
+func (schema *Schema) ValidateValue(file string, value interface{}) (SchemaErrors, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	errors := schema.Validate(file, node)
+	for i := range errors {
+		errors[i].Line, errors[i].Column = 0, 0
+	}
+	return errors, nil
+}
+
+// schemaValidation is the state of one validation.
+type schemaValidation struct {
+	root   *Schema
+	file   string
+	errors SchemaErrors
+}
+
+func (v *schemaValidation) fail(node *yaml.Node, path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "."
+	}
+	v.errors = append(v.errors, SchemaError{
+		File: v.file, Path: path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// check validates the node against a sub-schema without reporting, for anyOf, oneOf and not.
+func (v *schemaValidation) check(schema *Schema, node *yaml.Node) bool {
+	sub := schemaValidation{root: v.root, file: v.file}
+	sub.validate(schema, node, "")
+	return len(sub.errors) == 0
+}
+
+func (v *schemaValidation) validate(schema *Schema, node *yaml.Node, path string) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if schema.never {
+		v.fail(node, path, "not allowed")
+		return
+	}
+	if schema.Ref != "" {
+		ref, err := v.resolve(schema.Ref)
+		if err != nil {
+			v.fail(node, path, "%v", err)
+			return
+		}
+		v.validate(ref, node, path)
+	}
+	if len(schema.Type) > 0 && !slices.ContainsFunc(schema.Type, func(t string) bool { return nodeHasType(node, t) }) {
+		v.fail(node, path, "must be %s, not %s", strings.Join(schema.Type, " or "), nodeType(node))
+		return
+	}
+	if schema.Enum != nil && !slices.ContainsFunc(schema.Enum, func(e interface{}) bool { return nodeEquals(node, e) }) {
+		v.fail(node, path, "must be one of %v", schema.Enum)
+	}
+	if schema.Const != nil && !nodeEquals(node, schema.Const) {
+		v.fail(node, path, "must be %v", schema.Const)
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.validateMapping(schema, node, path)
+	case yaml.SequenceNode:
+		v.validateSequence(schema, node, path)
+	case yaml.ScalarNode:
+		v.validateScalar(schema, node, path)
+	}
+	for _, sub := range schema.AllOf {
+		v.validate(sub, node, path)
+	}
+	if len(schema.AnyOf) > 0 && !slices.ContainsFunc(schema.AnyOf, func(s *Schema) bool { return v.check(s, node) }) {
+		v.fail(node, path, "does not match any of the allowed schemas")
+	}
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		for _, sub := range schema.OneOf {
+			if v.check(sub, node) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(node, path, "must match exactly one of the allowed schemas, matched %d", matched)
+		}
+	}
+	if schema.Not != nil && v.check(schema.Not, node) {
+		v.fail(node, path, "matches a schema that is not allowed")
+	}
+}
+
+func (v *schemaValidation) validateMapping(schema *Schema, node *yaml.Node, path string) {
+	pairs := mappingPairs(node)
+	for _, key := range schema.Required {
+		if !slices.ContainsFunc(pairs, func(pair [2]*yaml.Node) bool { return pair[0].Value == key }) {
+			v.fail(node, path, "missing required property '%s'", key)
+		}
+	}
+	for _, pair := range pairs {
+		key, value := pair[0].Value, pair[1]
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		matched := false
+		if sub, ok := schema.Properties[key]; ok {
+			v.validate(sub, value, keyPath)
+			matched = true
+		}
+		for pattern, sub := range schema.PatternProperties {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				v.fail(pair[0], keyPath, "invalid schema pattern '%s': %v", pattern, err)
+				continue
+			}
+			if re.MatchString(key) {
+				v.validate(sub, value, keyPath)
+				matched = true
+			}
+		}
+		if !matched && schema.AdditionalProperties != nil {
+			if schema.AdditionalProperties.never {
+				v.fail(pair[0], keyPath, "unknown property '%s'", key)
+			} else {
+				v.validate(schema.AdditionalProperties, value, keyPath)
+			}
+		}
+	}
+}
+
+func (v *schemaValidation) validateSequence(schema *Schema, node *yaml.Node, path string) {
+	if schema.MinItems != nil && len(node.Content) < *schema.MinItems {
+		v.fail(node, path, "must have at least %d items", *schema.MinItems)
+	}
+	if schema.MaxItems != nil && len(node.Content) > *schema.MaxItems {
+		v.fail(node, path, "must have at most %d items", *schema.MaxItems)
+	}
+	if schema.Items != nil {
+		for i, item := range node.Content {
+			v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (v *schemaValidation) validateScalar(schema *Schema, node *yaml.Node, path string) {
+	length := len([]rune(node.Value))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		v.fail(node, path, "must be at least %d characters", *schema.MinLength)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.fail(node, path, "must be at most %d characters", *schema.MaxLength)
+	}
+	if schema.Pattern != "" {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			v.fail(node, path, "invalid schema pattern '%s': %v", schema.Pattern, err)
+		} else if !re.MatchString(node.Value) {
+			v.fail(node, path, "must match '%s'", schema.Pattern)
+		}
+	}
+	if schema.Minimum != nil || schema.Maximum != nil {
+		n, err := strconv.ParseFloat(node.Value, 64)
+		if err != nil || math.IsNaN(n) {
+			return
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			v.fail(node, path, "must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			v.fail(node, path, "must be at most %v", *schema.Maximum)
+		}
+	}
+}
+
+// resolve finds the definition of a local $ref, e.g. #/definitions/feature
+func (v *schemaValidation) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	for prefix, definitions := range map[string]map[string]*Schema{
+		"#/definitions/": v.root.Definitions,
+		"#/$defs/":       v.root.Defs,
+	} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			if definition, ok := definitions[name]; ok {
+				return definition, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown schema reference '%s'", ref)
+}
+
+// mappingPairs returns the key and value nodes of the mapping, the merge keys (<<) are replaced by the merged pairs.
+func mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			for value.Kind == yaml.AliasNode {
+				value = value.Alias
+			}
+			merged := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				merged = value.Content
+			}
+			for _, m := range merged {
+				for m.Kind == yaml.AliasNode {
+					m = m.Alias
+				}
+				if m.Kind == yaml.MappingNode {
+					pairs = append(pairs, mappingPairs(m)...)
+				}
+			}
+			continue
+		}
+		pairs = append(pairs, [2]*yaml.Node{key, value})
+	}
+	return pairs
+}
+
+// nodeType returns the JSON Schema type of the node.
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+func nodeHasType(node *yaml.Node, schemaType string) bool {
+	actual := nodeType(node)
+	return actual == schemaType || (schemaType == "number" && actual == "integer")
+}
+
+// nodeEquals compares a scalar node with a value of the schema.
+func nodeEquals(node *yaml.Node, value interface{}) bool {
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+	var decoded interface{}
+	if err := node.Decode(&decoded); err != nil {
+		return false
+	}
+	if n, ok := value.(float64); ok && nodeHasType(node, "number") {
+		f, err := toFloat(decoded)
+		return err == nil && f == n
+	}
+	return decoded == value
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := loadSchema([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "pattern": "^[A-Z]"},
+			"size": {"type": "integer", "minimum": 1, "maximum": 10},
+			"kind": {"enum": ["a", "b", 3]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"ref": {"$ref": "#/definitions/named"},
+			"either": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+			"not": {"not": {"const": "forbidden"}}
+		},
+		"patternProperties": {"^x-": true},
+		"additionalProperties": false,
+		"required": ["name"],
+		"definitions": {
+			"named": {"type": "object", "required": ["name"]}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("loadSchema() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{name: "valid", yaml: "name: Foo\nsize: 3\nkind: 3\ntags: [a]\nref: {name: x}\neither: 1\nx-note: {any: thing}\n"},
+		{name: "unknown property", yaml: "name: Foo\nnaem: Foo\n", want: []string{"config.yaml:2:1: naem: unknown property 'naem'"}},
+		{name: "missing required", yaml: "size: 1\n", want: []string{"config.yaml:1:1: .: missing required property 'name'"}},
+		{
+			name: "scalars",
+			yaml: "name: f\nsize: 11\nkind: c\n",
+			want: []string{
+				"config.yaml:1:7: name: must be at least 2 characters",
+				"config.yaml:1:7: name: must match '^[A-Z]'",
+				"config.yaml:2:7: size: must be at most 10",
+				"config.yaml:3:7: kind: must be one of [a b 3]",
+			},
+		},
+		{name: "wrong type", yaml: "name: Foo\nsize: ten\n", want: []string{"config.yaml:2:7: size: must be integer, not string"}},
+		{
+			name: "items",
+			yaml: "name: Foo\ntags:\n  - a\n  - [b]\n  - c\n",
+			want: []string{"config.yaml:3:3: tags: must have at most 2 items", "config.yaml:4:5: tags[1]: must be string, not array"},
+		},
+		{name: "ref", yaml: "name: Foo\nref: {}\n", want: []string{"config.yaml:2:6: ref: missing required property 'name'"}},
+		{name: "oneOf", yaml: "name: Foo\neither: [1]\n", want: []string{"config.yaml:2:9: either: must match exactly one of the allowed schemas, matched 0"}},
+		{name: "not", yaml: "name: Foo\nnot: forbidden\n", want: []string{"config.yaml:2:6: not: matches a schema that is not allowed"}},
+		{name: "merge key", yaml: "base: &base {name: Foo}\n", want: []string{"config.yaml:1:1: .: missing required property 'name'", "config.yaml:1:1: base: unknown property 'base'"}},
+		{name: "empty", yaml: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &yaml.Node{}
+			if err := yaml.Unmarshal([]byte(tt.yaml), node); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			got := make([]string, 0)
+			for _, e := range schema.Validate("config.yaml", node) {
+				got = append(got, e.Error())
+			}
+			if tt.want == nil {
+				tt.want = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSchema_Overrides(t *testing.T) {
+	schema, err := loadSchema(
+		defaultConfigSchema,
+		[]byte("definitions:\n  feature:\n    properties:\n      owner: {type: string}\n"),
+		[]byte(`{"definitions": {"feature": {"properties": {"priority": null}}}}`),
+	)
+	if err != nil {
+		t.Fatalf("loadSchema() error = %v", err)
+	}
+	feature := schema.Definitions["feature"]
+	if feature.Properties["owner"] == nil || feature.Properties["feature-set"] == nil {
+		t.Errorf("loadSchema() override not merged: %v", feature.Properties)
+	}
+	if _, ok := feature.Properties["priority"]; ok {
+		t.Errorf("loadSchema() priority not removed")
+	}
+	if _, err := loadSchema(defaultConfigSchema, []byte("{")); err == nil {
+		t.Errorf("loadSchema() invalid override accepted")
+	}
+}
+
+func TestLoadSchema_UnsupportedKeywords(t *testing.T) {
+	tests := []struct {
+		schema  string
+		wantErr string
+	}{
+		{`{"type": "string", "format": "email"}`, "schema: #: unsupported keyword 'format'"},
+		{`{"properties": {"tags": {"type": "array", "uniqueItems": true}}}`,
+			"schema: #/properties/tags: unsupported keyword 'uniqueItems'"},
+		{`{"definitions": {"n": {"exclusiveMinimum": 0}}}`, "schema: #/definitions/n: unsupported keyword 'exclusiveMinimum'"},
+		{`{"anyOf": [true, {"if": {}}]}`, "schema: #/anyOf/1: unsupported keyword 'if'"},
+		{`{"additionalProperties": {"minProperties": 1}}`, "schema: #/additionalProperties: unsupported keyword 'minProperties', did you mean 'properties'?"},
+		{`{"items": [{"type": "string"}]}`, "schema: #/items: a schema is an object or a boolean"},
+		{`{"requried": ["name"]}`, "schema: #: unsupported keyword 'requried', did you mean 'required'?"},
+	}
+	for _, tt := range tests {
+		if _, err := loadSchema([]byte(tt.schema)); err == nil || err.Error() != tt.wantErr {
+			t.Errorf("loadSchema(%s) error = %v, want %s", tt.schema, err, tt.wantErr)
+		}
+	}
+	if _, err := loadSchema(defaultConfigSchema, []byte(`{"definitions": {"feature": {"propertyNames": {}}}}`)); err == nil {
+		t.Errorf("loadSchema() override with propertyNames accepted")
+	}
+	if _, err := loadSchema([]byte(`{"$schema": "x", "title": "t", "default": {"format": 1}, "enum": [{"if": 1}]}`)); err != nil {
+		t.Errorf("loadSchema() annotations rejected: %v", err)
+	}
+}
+
+// The bundled schema accepts the sample config and catches misspelled keys.
+func TestDefaultConfigSchema(t *testing.T) {
+	schema, err := loadSchema(defaultConfigSchema)
+	if err != nil {
+		t.Fatalf("loadSchema() error = %v", err)
+	}
+	sample, err := os.ReadFile("../samples/configs/config.yaml")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		yaml string
+		want int
+	}{
+		{name: "sample", yaml: string(sample)},
+		{name: "groups", yaml: "_groups:\n  web: [Foo, Bar]\nFoo:\n  feature-set: [one, two]\n  x-owner: team\n"},
+		{name: "misspelled", yaml: "Foo:\n  feature_set: one\n", want: 1},
+		{name: "invalid group", yaml: "_groups:\n  web: Foo\n", want: 1},
+		{name: "parameter without name", yaml: "Foo:\n  parameters:\n    - property: foo\n", want: 1},
+		{name: "dep in list", yaml: "Foo:\n  deps:\n    - {name: bar, in: [A, B]}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &yaml.Node{}
+			if err := yaml.Unmarshal([]byte(tt.yaml), node); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got := schema.Validate("config.yaml", node); len(got) != tt.want {
+				t.Errorf("Validate() got = %v, want %d errors", got, tt.want)
+			}
+		})
+	}
+}
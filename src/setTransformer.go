@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// SelfMapper returns the input itself, it's used to compare plain elements like strings.
+func SelfMapper(input interface{}) interface{} {
+	return input
+}
+
+// DuplicatePolicy decides which element survives when several elements share the same key.
+type DuplicatePolicy int
+
+const (
+	// KeepFirst keeps the first element, at the position of the first element.
+	KeepFirst DuplicatePolicy = iota
+	// KeepLast keeps the last element, at the position of the last element.
+	KeepLast
+	// MergeDuplicates merges the fields of map elements into the first element, later fields win.
+	// Elements that are not maps are treated as KeepFirst.
+	MergeDuplicates
+	// FailOnDuplicate aborts with an error.
+	FailOnDuplicate
+)
+
+// ParseDuplicatePolicy converts the policy name (first, last, merge, error) to DuplicatePolicy.
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	switch name {
+	case "first", "":
+		return KeepFirst, nil
+	case "last":
+		return KeepLast, nil
+	case "merge":
+		return MergeDuplicates, nil
+	case "error":
+		return FailOnDuplicate, nil
+	}
+	return KeepFirst, fmt.Errorf("unknown duplicate policy '%s'", name)
+}
+
+// ListDistinctTransformer removes the elements with duplicated keys from a list.
+type ListDistinctTransformer struct {
+	keyMapper Mapper
+	policy    DuplicatePolicy
+}
+
+func (config ListDistinctTransformer) Transform(input interface{}) (interface{}, error) {
+	if input == nil {
+		return nil, errors.New("ListDistinctTransformer: Input is nil")
+	}
+	if reflect.TypeOf(input).Kind() != reflect.Slice {
+		return nil, errors.New("ListDistinctTransformer: Input is not a list")
+	}
+	listV := reflect.ValueOf(input)
+	records := make([]interface{}, 0)
+	// the position of the key in records
+	positions := make(map[string]int)
+	// the positions in records replaced by a later element
+	replaced := make(map[int]bool)
+	for i := 0; i < listV.Len(); i++ {
+		el := listV.Index(i).Interface()
+		key := fmt.Sprint(config.keyMapper(el))
+		pos, ok := positions[key]
+		if !ok {
+			positions[key] = len(records)
+			records = append(records, el)
+			continue
+		}
+		switch config.policy {
+		case KeepLast:
+			replaced[pos] = true
+			positions[key] = len(records)
+			records = append(records, el)
+		case MergeDuplicates:
+			if reflect.TypeOf(el).Kind() == reflect.Map && reflect.TypeOf(records[pos]).Kind() == reflect.Map {
+				merged, _ := copyToStringMap(records[pos])
+				update, _ := copyToStringMap(el)
+				for k, v := range update {
+					merged[k] = v
+				}
+				records[pos] = merged
+			}
+		case FailOnDuplicate:
+			return nil, fmt.Errorf("ListDistinctTransformer: Duplicated key '%s'", key)
+		}
+	}
+	if len(replaced) > 0 {
+		compacted := make([]interface{}, 0, len(positions))
+		for i, el := range records {
+			if !replaced[i] {
+				compacted = append(compacted, el)
+			}
+		}
+		records = compacted
+	}
+	return records, nil
+}
+
+type SetOperation int
+
+const (
+	// Union keeps the distinct elements of both lists, the input list first.
+	Union SetOperation = iota
+	// Intersection keeps the distinct elements of the input list that are also in the other list.
+	Intersection
+	// Difference keeps the distinct elements of the input list that are not in the other list.
+	Difference
+)
+
+// ListSetTransformer combines the input list with another list by comparing the keys of the elements.
+type ListSetTransformer struct {
+	other     interface{}
+	keyMapper Mapper
+	operation SetOperation
+}
+
+func (config ListSetTransformer) Transform(input interface{}) (interface{}, error) {
+	if input == nil {
+		return nil, errors.New("ListSetTransformer: Input is nil")
+	}
+	if reflect.TypeOf(input).Kind() != reflect.Slice {
+		return nil, errors.New("ListSetTransformer: Input is not a list")
+	}
+	if config.other == nil || reflect.TypeOf(config.other).Kind() != reflect.Slice {
+		return nil, errors.New("ListSetTransformer: Other is not a list")
+	}
+	otherV := reflect.ValueOf(config.other)
+	otherKeys := make(map[string]bool)
+	for i := 0; i < otherV.Len(); i++ {
+		otherKeys[fmt.Sprint(config.keyMapper(otherV.Index(i).Interface()))] = true
+	}
+	seen := make(map[string]bool)
+	records := make([]interface{}, 0)
+	collect := func(listV reflect.Value, keep func(key string) bool) {
+		for i := 0; i < listV.Len(); i++ {
+			el := listV.Index(i).Interface()
+			key := fmt.Sprint(config.keyMapper(el))
+			if seen[key] || !keep(key) {
+				continue
+			}
+			seen[key] = true
+			records = append(records, el)
+		}
+	}
+	listV := reflect.ValueOf(input)
+	switch config.operation {
+	case Union:
+		collect(listV, func(string) bool { return true })
+		collect(otherV, func(string) bool { return true })
+	case Intersection:
+		collect(listV, func(key string) bool { return otherKeys[key] })
+	case Difference:
+		collect(listV, func(key string) bool { return !otherKeys[key] })
+	}
+	return records, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListDistinctTransformer_Transform(t *testing.T) {
+	type fields struct {
+		keyMapper Mapper
+		policy    DuplicatePolicy
+	}
+	type args struct {
+		input interface{}
+	}
+	features := []interface{}{
+		map[string]interface{}{"Name": "Foo", "priority": "A01"},
+		map[string]interface{}{"Name": "Bar", "priority": "A02"},
+		map[string]interface{}{"Name": "Foo", "owner": "team-a"},
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "keep first",
+			fields: fields{
+				keyMapper: SelfMapper,
+				policy:    KeepFirst,
+			},
+			args: args{
+				input: []string{"foo", "bar", "foo", "baz"},
+			},
+			want: []interface{}{"foo", "bar", "baz"},
+		},
+		{
+			name: "keep last",
+			fields: fields{
+				keyMapper: StringMapMapper("Name"),
+				policy:    KeepLast,
+			},
+			args: args{
+				input: features,
+			},
+			want: []interface{}{features[1], features[2]},
+		},
+		{
+			name: "merge",
+			fields: fields{
+				keyMapper: StringMapMapper("Name"),
+				policy:    MergeDuplicates,
+			},
+			args: args{
+				input: features,
+			},
+			want: []interface{}{
+				map[string]interface{}{"Name": "Foo", "priority": "A01", "owner": "team-a"},
+				features[1],
+			},
+		},
+		{
+			name: "error",
+			fields: fields{
+				keyMapper: SelfMapper,
+				policy:    FailOnDuplicate,
+			},
+			args: args{
+				input: []string{"foo", "bar", "foo"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ListDistinctTransformer{
+				keyMapper: tt.fields.keyMapper,
+				policy:    tt.fields.policy,
+			}
+			got, err := config.Transform(tt.args.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSetTransformer_Transform(t *testing.T) {
+	type fields struct {
+		other     interface{}
+		operation SetOperation
+	}
+	type args struct {
+		input interface{}
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "union",
+			fields: fields{
+				other:     []string{"baz", "foo", "qux"},
+				operation: Union,
+			},
+			args: args{
+				input: []string{"foo", "bar", "foo", "baz"},
+			},
+			want: []interface{}{"foo", "bar", "baz", "qux"},
+		},
+		{
+			name: "intersection",
+			fields: fields{
+				other:     []string{"baz", "foo", "qux"},
+				operation: Intersection,
+			},
+			args: args{
+				input: []string{"foo", "bar", "foo", "baz"},
+			},
+			want: []interface{}{"foo", "baz"},
+		},
+		{
+			name: "difference",
+			fields: fields{
+				other:     []string{"baz", "foo", "qux"},
+				operation: Difference,
+			},
+			args: args{
+				input: []string{"foo", "bar", "foo", "baz"},
+			},
+			want: []interface{}{"bar"},
+		},
+		{
+			name: "other is not a list",
+			fields: fields{
+				other: "foo",
+			},
+			args: args{
+				input: []string{"foo"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ListSetTransformer{
+				other:     tt.fields.other,
+				keyMapper: SelfMapper,
+				operation: tt.fields.operation,
+			}
+			got, err := config.Transform(tt.args.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    DuplicatePolicy
+		wantErr bool
+	}{
+		{name: "first", want: KeepFirst},
+		{name: "last", want: KeepLast},
+		{name: "merge", want: MergeDuplicates},
+		{name: "error", want: FailOnDuplicate},
+		{name: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDuplicatePolicy(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDuplicatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuplicatePolicy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
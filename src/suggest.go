@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The number of close matches suggested
+const maxSuggestions = 3
+
+// closeMatches returns the candidates close to the name by edit distance, ignoring the case, the closest first. A
+// candidate is close when at most a third of the name, and at least one letter, differs, but not the whole name.
+func closeMatches(name string, candidates []string) []string {
+	maxDistance := min(max(1, len(name)/3), len(name)-1)
+	type match struct {
+		candidate string
+		distance  int
+	}
+	matches := make([]match, 0)
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		if distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); distance <= maxDistance {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	slices.SortFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.candidate, b.candidate)
+	})
+	closest := make([]string, 0, maxSuggestions)
+	for _, m := range matches[:min(len(matches), maxSuggestions)] {
+		closest = append(closest, m.candidate)
+	}
+	return closest
+}
+
+// didYouMean returns the close matches of the name as the end of a message, e.g. ", did you mean 'Foo'?", empty if
+// there are none.
+func didYouMean(name string, candidates []string) string {
+	matches := closeMatches(name, candidates)
+	if len(matches) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(matches))
+	for i, m := range matches {
+		quoted[i] = fmt.Sprintf("'%s'", m)
+	}
+	return fmt.Sprintf(", did you mean %s?", strings.Join(quoted, " or "))
+}
+
+// levenshtein returns the number of single rune insertions, deletions and substitutions turning a into b.
+func levenshtein(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
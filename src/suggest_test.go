@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCloseMatches(t *testing.T) {
+	candidates := []string{"Foo", "FooBar", "Bar", "Baz", "web-server", "web-service"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "Fooo", want: []string{"Foo"}},
+		{name: "foo", want: []string{"Foo"}},
+		{name: "Foo", want: []string{}},
+		{name: "Bax", want: []string{"Bar", "Baz"}},
+		{name: "web-servce", want: []string{"web-service", "web-server"}},
+		{name: "Qux", want: []string{}},
+		{name: "B", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closeMatches(tt.name, candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("closeMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	if got := didYouMean("Bax", []string{"Baz", "Bar"}); got != ", did you mean 'Bar' or 'Baz'?" {
+		t.Errorf("didYouMean() = %q", got)
+	}
+	if got := didYouMean("Qux", []string{"Baz", "Bar"}); got != "" {
+		t.Errorf("didYouMean() = %q, want none", got)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"Größe", "Grösse", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
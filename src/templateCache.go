@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path"
+	"sync"
+	"text/template"
+)
+
+// TemplateCache loads the templates of the feature sets rendered concurrently. The partials of an engine and a dir are
+// parsed once, each template is parsed on a copy of them.
+type TemplateCache struct {
+	templateDir string
+	libraryDir  string
+	functions   template.FuncMap
+	mu          sync.Mutex
+	partials    map[string]*cachedPartials
+}
+
+type cachedPartials struct {
+	once sync.Once
+	set  templateSet
+	// The stamps of the files read to parse the partials
+	files map[string]fileStamp
+	err   error
+}
+
+func NewTemplateCache(args Args, lookup PropertiesLookup) *TemplateCache {
+	return &TemplateCache{
+		templateDir: args.TemplateDir,
+		libraryDir:  args.TemplateLibDir,
+		functions:   prepareTemplateFunctions(lookup),
+		partials:    make(map[string]*cachedPartials),
+	}
+}
+
+// Load loads the template file like TemplateLoader.Load. The files read are recorded by the tracker, the partials too
+// although they were parsed for another template.
+func (cache *TemplateCache) Load(file string, tracker *FileTracker) (Executable, FrontMatter, error) {
+	loader := cache.loader(tracker)
+	frontMatter, content, err := loader.read(file)
+	if err != nil {
+		return nil, frontMatter, err
+	}
+	partials := cache.partialsOf(frontMatter.Engine, path.Dir(file))
+	tracker.addAll(partials.files)
+	if partials.err != nil {
+		return nil, frontMatter, partials.err
+	}
+	set, err := partials.set.clone()
+	if err != nil {
+		return nil, frontMatter, err
+	}
+	tmpl, err := loader.parseTemplate(set, file, content)
+	return tmpl, frontMatter, err
+}
+
+func (cache *TemplateCache) partialsOf(engine string, dir string) *cachedPartials {
+	if engine == "" {
+		engine = "html"
+	}
+	cache.mu.Lock()
+	partials, ok := cache.partials[engine+":"+dir]
+	if !ok {
+		partials = &cachedPartials{}
+		cache.partials[engine+":"+dir] = partials
+	}
+	cache.mu.Unlock()
+	partials.once.Do(func() {
+		tracker := NewFileTracker()
+		partials.set, partials.err = cache.loader(tracker).partials(engine, dir)
+		partials.files = tracker.Stamps()
+	})
+	return partials
+}
+
+func (cache *TemplateCache) loader(tracker *FileTracker) TemplateLoader {
+	loader := TemplateLoader{
+		templates: tracker.FS(cache.templateDir),
+		functions: cache.functions,
+	}
+	if cache.libraryDir != "" {
+		loader.library = tracker.FS(cache.libraryDir)
+	}
+	return loader
+}
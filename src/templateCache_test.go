@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateCache_Load(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"_greet.tmpl":     `{{ define "greet" }}Hello{{ end }}`,
+		"one.tmpl":        `{{ template "greet" }} one {{ block "name" . }}{{ .name }}{{ end }}`,
+		"two.tmpl":        `{{ template "greet" }} two{{ define "name" }}redefined{{ end }}`,
+		"three/_sub.tmpl": `{{ define "greet" }}Hi{{ end }}`,
+		"three/a.tmpl":    `{{ template "greet" }} three`,
+	}
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache := NewTemplateCache(Args{TemplateDir: dir}, PropertiesLookup{})
+	tests := []struct {
+		file  string
+		want  string
+		files []string
+	}{
+		{file: "one.tmpl", want: "Hello one x", files: []string{".", "_greet.tmpl", "one.tmpl"}},
+		{file: "two.tmpl", want: "Hello two", files: []string{".", "_greet.tmpl", "two.tmpl"}},
+		// the template defined by two.tmpl doesn't leak into the partials
+		{file: "one.tmpl", want: "Hello one x", files: []string{".", "_greet.tmpl", "one.tmpl"}},
+		{file: "three/a.tmpl", want: "Hi three", files: []string{".", "_greet.tmpl", "three", "three/_sub.tmpl", "three/a.tmpl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			tracker := NewFileTracker()
+			tmpl, _, err := cache.Load(tt.file, tracker)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			buffer := bytes.Buffer{}
+			if err := tmpl.Execute(&buffer, map[string]interface{}{"name": "x"}); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := buffer.String(); got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+			want := make([]string, len(tt.files))
+			for i, file := range tt.files {
+				want[i] = filepath.Join(dir, file)
+			}
+			if got := tracker.Files(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Files() = %v, want %v", got, want)
+			}
+		})
+	}
+	if len(cache.partials) != 2 {
+		t.Errorf("partials parsed %d times, want 2", len(cache.partials))
+	}
+}
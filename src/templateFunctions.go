@@ -0,0 +1,534 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// templateFunction is an entry of the template function library, the reference documentation is generated from it.
+type templateFunction struct {
+	name     string
+	category string
+	fn       interface{}
+	// How the function is called, e.g. `indent 4 .text`
+	usage string
+	doc   string
+}
+
+// The order of the categories in the reference documentation
+var templateFunctionCategories = []string{"Properties", "Strings", "Defaults", "Encoding", "Lists", "Math", "Dates"}
+
+// templateFunctions returns the template function library, the property functions look up the properties.
+func templateFunctions(lookup PropertiesLookup) []templateFunction {
+	return []templateFunction{
+		{"hasProperty", "Properties", lookup.HasProperty, `hasProperty "key"`, "Returns true if the key is found in any of the property files."},
+		{"getProperty", "Properties", lookup.GetProperty, `getProperty "key"`, "Returns the value of the key from the first property file that has it."},
+
+		{"upper", "Strings", strings.ToUpper, `upper .s`, "Converts the string to upper case."},
+		{"lower", "Strings", strings.ToLower, `lower .s`, "Converts the string to lower case."},
+		{"title", "Strings", title, `title .s`, "Converts the first letter of each word to upper case."},
+		{"camelCase", "Strings", camelCase, `camelCase .s`, "Converts `foo-bar baz` to `fooBarBaz`."},
+		{"snakeCase", "Strings", snakeCase, `snakeCase .s`, "Converts `fooBar baz` to `foo_bar_baz`."},
+		{"kebabCase", "Strings", kebabCase, `kebabCase .s`, "Converts `fooBar baz` to `foo-bar-baz`."},
+		{"trim", "Strings", strings.TrimSpace, `trim .s`, "Removes the leading and trailing white space."},
+		{"trimPrefix", "Strings", trimPrefix, `trimPrefix "prefix" .s`, "Removes the prefix if the string starts with it."},
+		{"trimSuffix", "Strings", trimSuffix, `trimSuffix "suffix" .s`, "Removes the suffix if the string ends with it."},
+		{"replace", "Strings", replace, `replace "old" "new" .s`, "Replaces every occurrence of old with new."},
+		{"contains", "Strings", contains, `contains "sub" .s`, "Returns true if the string contains sub."},
+		{"indent", "Strings", indent, `indent 4 .s`, "Indents every line of the string by the number of spaces."},
+		{"nindent", "Strings", nindent, `nindent 4 .s`, "Same as indent, with a new line in front. Useful after a YAML key."},
+
+		{"default", "Defaults", defaultValue, `default "value" .v`, "Returns the value when .v is empty: nil, false, 0, empty string, list or map."},
+		{"required", "Defaults", required, `required "message" .v`, "Fails the rendering with the message when .v is empty, otherwise returns .v."},
+
+		{"toYaml", "Encoding", toYaml, `toYaml .v`, "Encodes the value as YAML, without the trailing new line."},
+		{"toJson", "Encoding", toJson, `toJson .v`, "Encodes the value as JSON."},
+		{"fromYaml", "Encoding", fromYaml, `fromYaml .s`, "Decodes the YAML or JSON string."},
+		{"sha256", "Encoding", sha256Sum, `sha256 .s`, "Returns the hex encoded SHA-256 of the string."},
+		{"b64enc", "Encoding", b64enc, `b64enc .s`, "Encodes the string as base64."},
+		{"b64dec", "Encoding", b64dec, `b64dec .s`, "Decodes the base64 string."},
+
+		{"list", "Lists", list, `list "a" "b"`, "Returns the arguments as a list."},
+		{"first", "Lists", first, `first .list`, "Returns the first element, nil when the list is empty."},
+		{"last", "Lists", last, `last .list`, "Returns the last element, nil when the list is empty."},
+		{"uniq", "Lists", uniq, `uniq .list`, "Removes the duplicated elements, the first one is kept."},
+		{"sortBy", "Lists", sortBy, `sortBy "key" .list`, "Sorts a list of maps by the value of the key, numbers are compared as numbers."},
+		{"where", "Lists", where, `where "key" "value" .list`, "Keeps the maps whose value of the key equals the value, compared as text. A list value matches if it contains the value."},
+		{"pluck", "Lists", pluck, `pluck "key" .list`, "Returns the value of the key of every map in the list."},
+		{"get", "Lists", get, `get "key" .map`, "Returns the value of the key of a map or a typed feature, nil if missing. Works in both feature models."},
+
+		{"add", "Math", add, `add 1 2`, "Adds the numbers. Numbers may be strings, e.g. property values. Integers stay integers."},
+		{"sub", "Math", sub, `sub 3 1`, "Subtracts the second number from the first."},
+		{"mul", "Math", mul, `mul 2 3`, "Multiplies the numbers."},
+		{"div", "Math", div, `div 6 3`, "Divides the first number by the second, integer division for integers."},
+		{"mod", "Math", mod, `mod 7 3`, "Returns the remainder of the integer division."},
+		{"max", "Math", maxNumber, `max 1 2`, "Returns the larger number."},
+		{"min", "Math", minNumber, `min 1 2`, "Returns the smaller number."},
+
+		{"now", "Dates", time.Now, `now`, "Returns the current time."},
+		{"date", "Dates", date, `date "2006-01-02" .t`, "Formats the time with a Go layout. The time may be a time, an RFC 3339 or 2006-01-02 string or unix seconds."},
+	}
+}
+
+// writeFunctionReference writes the markdown reference of the template functions.
+func writeFunctionReference(w io.Writer, functions []templateFunction) error {
+	sb := strings.Builder{}
+	sb.WriteString("# Template functions\n\n")
+	sb.WriteString("This file is generated from the template function library, run `go test -run TestFunctionReference -update` to update it.\n")
+	for _, category := range templateFunctionCategories {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", category))
+		sb.WriteString("| Function | Usage | Description |\n")
+		sb.WriteString("|---|---|---|\n")
+		for _, f := range functions {
+			if f.category == category {
+				sb.WriteString(fmt.Sprintf("| %s | `%s` | %s |\n", f.name, f.usage, f.doc))
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// words splits the string into words at white space, punctuation and lower to upper case changes.
+func words(s string) []string {
+	result := make([]string, 0)
+	current := make([]rune, 0)
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, string(current))
+			current = current[:0]
+		}
+	}
+	var prev rune
+	for _, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+		prev = r
+	}
+	flush()
+	return result
+}
+
+func title(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+func camelCase(s string) string {
+	sb := strings.Builder{}
+	for i, w := range words(s) {
+		w = strings.ToLower(w)
+		if i > 0 {
+			w = title(w)
+		}
+		sb.WriteString(w)
+	}
+	return sb.String()
+}
+
+func snakeCase(s string) string {
+	return strings.ToLower(strings.Join(words(s), "_"))
+}
+
+func kebabCase(s string) string {
+	return strings.ToLower(strings.Join(words(s), "-"))
+}
+
+// The string functions take the string last, so they can be used in pipelines
+func trimPrefix(prefix, s string) string {
+	return strings.TrimPrefix(s, prefix)
+}
+
+func trimSuffix(suffix, s string) string {
+	return strings.TrimSuffix(s, suffix)
+}
+
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+func contains(sub, s string) bool {
+	return strings.Contains(s, sub)
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func nindent(spaces int, s string) string {
+	return "\n" + indent(spaces, s)
+}
+
+// isEmpty returns true for nil and the zero value, and for empty lists and maps.
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+func defaultValue(def interface{}, v interface{}) interface{} {
+	if isEmpty(v) {
+		return def
+	}
+	return v
+}
+
+func required(message string, v interface{}) (interface{}, error) {
+	if isEmpty(v) {
+		return nil, errors.New(message)
+	}
+	return v, nil
+}
+
+// normalizeKeys converts the maps with non-string keys, as decoded by yaml, into map[string]interface{} recursively.
+func normalizeKeys(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = normalizeKeys(iter.Value().Interface())
+		}
+		return m
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		l := make([]interface{}, rv.Len())
+		for i := range l {
+			l[i] = normalizeKeys(rv.Index(i).Interface())
+		}
+		return l
+	}
+	return v
+}
+
+func toYaml(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+func toJson(v interface{}) (string, error) {
+	b, err := json.Marshal(normalizeKeys(v))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func fromYaml(s string) (interface{}, error) {
+	var v interface{}
+	err := yaml.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func sha256Sum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func b64enc(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func b64dec(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	return string(b), err
+}
+
+// toList converts any list to []interface{}.
+func toList(v interface{}) ([]interface{}, error) {
+	if v == nil {
+		return []interface{}{}, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("'%v' is not a list", v)
+	}
+	l := make([]interface{}, rv.Len())
+	for i := range l {
+		l[i] = rv.Index(i).Interface()
+	}
+	return l, nil
+}
+
+func list(items ...interface{}) []interface{} {
+	return items
+}
+
+func first(v interface{}) (interface{}, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[0], nil
+}
+
+func last(v interface{}) (interface{}, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[len(l)-1], nil
+}
+
+func uniq(v interface{}) (interface{}, error) {
+	step := ListDistinctTransformer{
+		keyMapper: SelfMapper,
+		policy:    KeepFirst,
+	}
+	return step.Transform(v)
+}
+
+func sortBy(key string, v interface{}) ([]interface{}, error) {
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	sorted := slices.Clone(l)
+	mapper := StringMapMapper(key)
+	slices.SortStableFunc(sorted, func(a, b interface{}) int {
+		va, vb := mapper(a), mapper(b)
+		na, errA := toFloat(va)
+		nb, errB := toFloat(vb)
+		if errA == nil && errB == nil {
+			return compareFloat(na, nb)
+		}
+		return strings.Compare(fmt.Sprint(va), fmt.Sprint(vb))
+	})
+	return sorted, nil
+}
+
+func where(key string, value interface{}, v interface{}) ([]interface{}, error) {
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	mapper := StringMapMapper(key)
+	records := make([]interface{}, 0)
+	for _, el := range l {
+		found := mapper(el)
+		if found == nil {
+			continue
+		}
+		if list, err := toList(found); err == nil {
+			if slices.ContainsFunc(list, func(item interface{}) bool { return fmt.Sprint(item) == fmt.Sprint(value) }) {
+				records = append(records, el)
+			}
+			continue
+		}
+		if fmt.Sprint(found) == fmt.Sprint(value) {
+			records = append(records, el)
+		}
+	}
+	return records, nil
+}
+
+func pluck(key string, v interface{}) ([]interface{}, error) {
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	mapper := StringMapMapper(key)
+	records := make([]interface{}, len(l))
+	for i, el := range l {
+		records[i] = mapper(el)
+	}
+	return records, nil
+}
+
+// get returns the value of the key, the same value the key functions like where and pluck see.
+func get(key string, v interface{}) interface{} {
+	return StringMapMapper(key)(v)
+}
+
+// toInt converts integers and strings holding an integer.
+func toInt(v interface{}) (int, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), nil
+	case reflect.String:
+		return strconv.Atoi(strings.TrimSpace(rv.String()))
+	}
+	return 0, fmt.Errorf("'%v' is not an integer", v)
+}
+
+// toFloat converts numbers and strings holding a number.
+func toFloat(v interface{}) (float64, error) {
+	if i, err := toInt(v); err == nil {
+		return float64(i), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+	}
+	return 0, fmt.Errorf("'%v' is not a number", v)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// arithmetic applies the integer operation when both numbers are integers, the float operation otherwise.
+func arithmetic(a, b interface{}, intOp func(int, int) (int, error), floatOp func(float64, float64) (float64, error)) (interface{}, error) {
+	ia, errA := toInt(a)
+	ib, errB := toInt(b)
+	if errA == nil && errB == nil {
+		return intOp(ia, ib)
+	}
+	fa, err := toFloat(a)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+	return floatOp(fa, fb)
+}
+
+func add(a, b interface{}) (interface{}, error) {
+	return arithmetic(a, b,
+		func(a, b int) (int, error) { return a + b, nil },
+		func(a, b float64) (float64, error) { return a + b, nil })
+}
+
+func sub(a, b interface{}) (interface{}, error) {
+	return arithmetic(a, b,
+		func(a, b int) (int, error) { return a - b, nil },
+		func(a, b float64) (float64, error) { return a - b, nil })
+}
+
+func mul(a, b interface{}) (interface{}, error) {
+	return arithmetic(a, b,
+		func(a, b int) (int, error) { return a * b, nil },
+		func(a, b float64) (float64, error) { return a * b, nil })
+}
+
+var errDivisionByZero = errors.New("division by zero")
+
+func div(a, b interface{}) (interface{}, error) {
+	return arithmetic(a, b,
+		func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errDivisionByZero
+			}
+			return a / b, nil
+		},
+		func(a, b float64) (float64, error) {
+			if b == 0 {
+				return 0, errDivisionByZero
+			}
+			return a / b, nil
+		})
+}
+
+func mod(a, b interface{}) (interface{}, error) {
+	ia, err := toInt(a)
+	if err != nil {
+		return nil, err
+	}
+	ib, err := toInt(b)
+	if err != nil {
+		return nil, err
+	}
+	if ib == 0 {
+		return nil, errDivisionByZero
+	}
+	return ia % ib, nil
+}
+
+func maxNumber(a, b interface{}) (interface{}, error) {
+	return arithmetic(a, b,
+		func(a, b int) (int, error) { return max(a, b), nil },
+		func(a, b float64) (float64, error) { return math.Max(a, b), nil })
+}
+
+func minNumber(a, b interface{}) (interface{}, error) {
+	return arithmetic(a, b,
+		func(a, b int) (int, error) { return min(a, b), nil },
+		func(a, b float64) (float64, error) { return math.Min(a, b), nil })
+}
+
+func date(layout string, v interface{}) (string, error) {
+	var t time.Time
+	switch value := v.(type) {
+	case time.Time:
+		t = value
+	case string:
+		var err error
+		if t, err = time.Parse(time.RFC3339, value); err != nil {
+			if t, err = time.Parse(time.DateOnly, value); err != nil {
+				return "", fmt.Errorf("'%s' is not a date", value)
+			}
+		}
+	default:
+		seconds, err := toInt(v)
+		if err != nil {
+			return "", fmt.Errorf("'%v' is not a date", v)
+		}
+		t = time.Unix(int64(seconds), 0).UTC()
+	}
+	return t.Format(layout), nil
+}
+
+// functionMap converts the template function library into a FuncMap.
+func functionMap(functions []templateFunction) template.FuncMap {
+	m := template.FuncMap{}
+	for _, f := range functions {
+		m[f.name] = f.fn
+	}
+	return m
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+	"text/template"
+)
+
+var updateReference = flag.Bool("update", false, "update the generated template function reference")
+
+// The generated reference documentation of the template functions
+const functionReferenceFile = "../docs/template-functions.md"
+
+func TestTemplateFunctions(t *testing.T) {
+	data := map[string]interface{}{
+		"text":  "line1\nline2",
+		"empty": "",
+		"map": map[interface{}]interface{}{
+			"name": "foo",
+			"list": []interface{}{1, 2},
+		},
+		"deps": []interface{}{
+			map[string]interface{}{"name": "a", "in": []interface{}{"A", "B"}},
+			FeatureDep{Name: "b", In: StringList{"B"}},
+			FeatureDep{Name: "c", In: StringList{"C"}},
+		},
+		"features": []interface{}{
+			map[string]interface{}{"Name": "Foo", "priority": "10", "in": "A"},
+			map[string]interface{}{"Name": "Bar", "priority": "9", "in": "B"},
+			map[string]interface{}{"Name": "Baz", "priority": "11", "in": "A"},
+		},
+	}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{name: "hasProperty", template: `{{ hasProperty "key1" }} {{ hasProperty "missing" }}`, want: "true false"},
+		{name: "getProperty", template: `{{ getProperty "key1" }}`, want: "value1"},
+		{name: "upper", template: `{{ upper "foo" }}`, want: "FOO"},
+		{name: "lower", template: `{{ "FOO" | lower }}`, want: "foo"},
+		{name: "title", template: `{{ title "foo bar" }}`, want: "Foo Bar"},
+		{name: "camelCase", template: `{{ camelCase "foo-bar baz" }}`, want: "fooBarBaz"},
+		{name: "snakeCase", template: `{{ snakeCase "fooBar baz" }}`, want: "foo_bar_baz"},
+		{name: "kebabCase", template: `{{ kebabCase "FooBar_baz" }}`, want: "foo-bar-baz"},
+		{name: "trim", template: `[{{ trim "  foo " }}]`, want: "[foo]"},
+		{name: "trimPrefix", template: `{{ "legacy-foo" | trimPrefix "legacy-" }}`, want: "foo"},
+		{name: "trimSuffix", template: `{{ "foo.tmpl" | trimSuffix ".tmpl" }}`, want: "foo"},
+		{name: "replace", template: `{{ "a-b-c" | replace "-" "_" }}`, want: "a_b_c"},
+		{name: "contains", template: `{{ "foobar" | contains "oba" }}`, want: "true"},
+		{name: "indent", template: `{{ indent 2 .text }}`, want: "  line1\n  line2"},
+		{name: "nindent", template: `key:{{ .text | nindent 2 }}`, want: "key:\n  line1\n  line2"},
+		{name: "default", template: `{{ .empty | default "def" }} {{ "set" | default "def" }}`, want: "def set"},
+		{name: "required", template: `{{ required "value missing" "ok" }}`, want: "ok"},
+		{name: "required, empty", template: `{{ required "value missing" .empty }}`, wantErr: true},
+		{name: "toYaml", template: `{{ toYaml .map }}`, want: "list:\n    - 1\n    - 2\nname: foo"},
+		{name: "toJson", template: `{{ toJson .map }}`, want: `{"list":[1,2],"name":"foo"}`},
+		{name: "fromYaml", template: `{{ $m := fromYaml "a: {b: 1}" }}{{ $m.a.b }}`, want: "1"},
+		{name: "sha256", template: `{{ sha256 "foo" }}`, want: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"},
+		{name: "b64enc", template: `{{ b64enc "foo" }}`, want: "Zm9v"},
+		{name: "b64dec", template: `{{ b64dec "Zm9v" }}`, want: "foo"},
+		{name: "list", template: `{{ list "a" "b" }}`, want: "[a b]"},
+		{name: "first", template: `{{ (first .features).Name }}`, want: "Foo"},
+		{name: "last", template: `{{ (last .features).Name }}`, want: "Baz"},
+		{name: "first, empty", template: `{{ first (list) }}`, want: "<no value>"},
+		{name: "uniq", template: `{{ uniq (list "a" "b" "a") }}`, want: "[a b]"},
+		{name: "sortBy", template: `{{ range sortBy "priority" .features }}{{ .Name }} {{ end }}`, want: "Bar Foo Baz "},
+		{name: "where", template: `{{ range where "in" "A" .features }}{{ .Name }} {{ end }}`, want: "Foo Baz "},
+		{name: "where, list value", template: `{{ pluck "name" (where "in" "B" .deps) }}`, want: "[a b]"},
+		{name: "pluck", template: `{{ pluck "Name" .features }}`, want: "[Foo Bar Baz]"},
+		{name: "get", template: `{{ range .deps }}{{ get "name" . }} {{ end }}{{ get "name" .map }}`, want: "a b c foo"},
+		{name: "get, missing", template: `{{ get "instance" (index .deps 1) }}`, want: "<no value>"},
+		{name: "add", template: `{{ add 1 2 }} {{ add "10" 1 }} {{ add 1.5 1 }}`, want: "3 11 2.5"},
+		{name: "sub", template: `{{ sub 3 1 }}`, want: "2"},
+		{name: "mul", template: `{{ mul 2 3 }}`, want: "6"},
+		{name: "div", template: `{{ div 7 2 }} {{ div 7.0 2 }}`, want: "3 3.5"},
+		{name: "div by zero", template: `{{ div 7 0 }}`, wantErr: true},
+		{name: "mod", template: `{{ mod 7 3 }}`, want: "1"},
+		{name: "max", template: `{{ max 1 2 }}`, want: "2"},
+		{name: "min", template: `{{ min 1 2 }}`, want: "1"},
+		{name: "not a number", template: `{{ add "foo" 1 }}`, wantErr: true},
+		{name: "now", template: `{{ if now.IsZero }}zero{{ else }}set{{ end }}`, want: "set"},
+		{name: "date", template: `{{ date "02 Jan 2006" "2024-03-01" }} {{ date "2006-01-02" 0 }}`, want: "01 Mar 2024 1970-01-01"},
+		{name: "date, invalid", template: `{{ date "2006" "yesterday" }}`, wantErr: true},
+	}
+	lookup := PropertiesLookup{
+		properties: []interface{}{
+			map[string]string{"key1": "value1"},
+		},
+	}
+	functions := functionMap(templateFunctions(lookup))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New(tt.name).Funcs(functions).Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			buffer := bytes.Buffer{}
+			err = tmpl.Execute(&buffer, data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && buffer.String() != tt.want {
+				t.Errorf("Execute() got = %q, want %q", buffer.String(), tt.want)
+			}
+		})
+	}
+}
+
+// Every function of the library must be in a documented category and tested above.
+func TestTemplateFunctions_Registry(t *testing.T) {
+	names := make(map[string]bool)
+	for _, f := range templateFunctions(PropertiesLookup{}) {
+		if names[f.name] {
+			t.Errorf("function %s registered twice", f.name)
+		}
+		names[f.name] = true
+		if f.usage == "" || f.doc == "" {
+			t.Errorf("function %s has no usage or doc", f.name)
+		}
+		found := false
+		for _, category := range templateFunctionCategories {
+			found = found || category == f.category
+		}
+		if !found {
+			t.Errorf("function %s has unknown category %s", f.name, f.category)
+		}
+	}
+}
+
+func TestFunctionReference(t *testing.T) {
+	buffer := bytes.Buffer{}
+	if err := writeFunctionReference(&buffer, templateFunctions(PropertiesLookup{})); err != nil {
+		t.Fatalf("writeFunctionReference() error = %v", err)
+	}
+	if *updateReference {
+		if err := os.WriteFile(functionReferenceFile, buffer.Bytes(), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	existing, err := os.ReadFile(functionReferenceFile)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(existing, buffer.Bytes()) {
+		t.Errorf("%s is out of date, run go test -run TestFunctionReference -update", functionReferenceFile)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"text/template"
+)
+
+// The pattern of the shared partials in the template dir and the library dir
+const partialPattern = "_*.tmpl"
+
+// The declaration of the base layout, it must be the first action of a template after the front-matter,
+// e.g. {{/* extends "_layout.tmpl" */}}
+var extendsDeclaration = regexp.MustCompile(`^(?:\{\{/\*\n*\*/}})?\s*\{\{-?\s*/\*\s*extends\s+"([^"]+)"\s*\*/\s*-?}}`)
+
+// Executable is a parsed template of either engine.
+type Executable interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// templateSet is a set of named templates of either engine.
+type templateSet interface {
+	parse(name string, content string) error
+	lookup(name string) Executable
+	// clone returns a copy of the set, the templates parsed into the copy don't change the set
+	clone() (templateSet, error)
+}
+
+// textTemplateSet is the template set of the text engine, the output is not escaped.
+type textTemplateSet struct {
+	root *template.Template
+}
+
+func (set textTemplateSet) parse(name string, content string) error {
+	_, err := set.root.New(name).Parse(content)
+	return err
+}
+
+func (set textTemplateSet) lookup(name string) Executable {
+	if t := set.root.Lookup(name); t != nil {
+		return t
+	}
+	return nil
+}
+
+func (set textTemplateSet) clone() (templateSet, error) {
+	root, err := set.root.Clone()
+	return textTemplateSet{root: root}, err
+}
+
+// htmlTemplateSet is the template set of the html engine, the default engine, the output is escaped by its context.
+type htmlTemplateSet struct {
+	root *htmltemplate.Template
+}
+
+func (set htmlTemplateSet) parse(name string, content string) error {
+	_, err := set.root.New(name).Parse(content)
+	return err
+}
+
+func (set htmlTemplateSet) lookup(name string) Executable {
+	if t := set.root.Lookup(name); t != nil {
+		return t
+	}
+	return nil
+}
+
+func (set htmlTemplateSet) clone() (templateSet, error) {
+	root, err := set.root.Clone()
+	return htmlTemplateSet{root: root}, err
+}
+
+// TemplateLoader loads the template of a feature set together with the shared partials. Every template is named by
+// its file name, so partials are used by {{ template "_deps.tmpl" . }} and errors point at the file.
+type TemplateLoader struct {
+	// The template dir
+	templates fs.FS
+	// Optional, a library dir with more partials, the partials of the template dir win
+	library   fs.FS
+	functions template.FuncMap
+}
+
+// Load parses the partials and the template file, the partials in the dir of the template file are loaded too.
+// When the template declares a base layout, the layout is returned and the {{ define }} of the template override the
+// {{ block }} of the layout. The front-matter of the template file is returned separately, its engine decides
+// the template engine of the whole set.
+func (loader TemplateLoader) Load(file string) (Executable, FrontMatter, error) {
+	frontMatter, content, err := loader.read(file)
+	if err != nil {
+		return nil, frontMatter, err
+	}
+	set, err := loader.partials(frontMatter.Engine, path.Dir(file))
+	if err != nil {
+		return nil, frontMatter, err
+	}
+	tmpl, err := loader.parseTemplate(set, file, content)
+	return tmpl, frontMatter, err
+}
+
+// read reads the template file and splits the front-matter from the template.
+func (loader TemplateLoader) read(file string) (FrontMatter, []byte, error) {
+	content, err := fs.ReadFile(loader.templates, file)
+	if err != nil {
+		return FrontMatter{}, nil, err
+	}
+	frontMatter, content, err := splitFrontMatter(file, content)
+	if err != nil {
+		return frontMatter, nil, err
+	}
+	switch frontMatter.Engine {
+	case "", "text", "html":
+		return frontMatter, content, nil
+	}
+	return frontMatter, nil, fmt.Errorf("%s: unknown engine '%s'", file, frontMatter.Engine)
+}
+
+// partials returns a new set of the engine with the partials of the library, of the template dir and of the dir.
+func (loader TemplateLoader) partials(engine string, dir string) (templateSet, error) {
+	var set templateSet = htmlTemplateSet{root: htmltemplate.New("").Funcs(htmltemplate.FuncMap(loader.functions))}
+	if engine == "text" {
+		set = textTemplateSet{root: template.New("").Funcs(loader.functions)}
+	}
+	if loader.library != nil {
+		if err := loader.parsePartials(set, loader.library, "."); err != nil {
+			return nil, fmt.Errorf("template library: %w", err)
+		}
+	}
+	if err := loader.parsePartials(set, loader.templates, "."); err != nil {
+		return nil, err
+	}
+	if dir != "." {
+		if err := loader.parsePartials(set, loader.templates, dir); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// parseTemplate adds the template file to the set of partials, it returns the layout the template extends if any, the
+// template otherwise.
+func (loader TemplateLoader) parseTemplate(set templateSet, file string, content []byte) (Executable, error) {
+	entry := file
+	if m := extendsDeclaration.FindSubmatch(content); m != nil {
+		entry = string(m[1])
+		if set.lookup(entry) == nil {
+			// the layout is not a partial, look for it in the template dir
+			if err := loader.parse(set, loader.templates, entry); err != nil {
+				return nil, fmt.Errorf("%s: layout: %w", file, err)
+			}
+		}
+	}
+	if err := set.parse(file, string(content)); err != nil {
+		return nil, err
+	}
+	return set.lookup(entry), nil
+}
+
+// parsePartials adds every partial in the dir of the filesystem to the set.
+func (loader TemplateLoader) parsePartials(set templateSet, filesystem fs.FS, dir string) error {
+	partials, err := fs.Glob(filesystem, path.Join(dir, partialPattern))
+	if err != nil {
+		return err
+	}
+	for _, partial := range partials {
+		if err := loader.parse(set, filesystem, partial); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse adds the file as a named template to the set.
+func (loader TemplateLoader) parse(set templateSet, filesystem fs.FS, file string) error {
+	content, err := fs.ReadFile(filesystem, file)
+	if err != nil {
+		return err
+	}
+	return set.parse(file, string(content))
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+func TestTemplateLoader_Load(t *testing.T) {
+	type fields struct {
+		templates fs.FS
+		library   fs.FS
+	}
+	type args struct {
+		file string
+	}
+	templates := fstest.MapFS{
+		"plain.tmpl": {
+			Data: []byte(`Hello {{ .name }}`),
+		},
+		"partial.tmpl": {
+			Data: []byte(`{{ template "_greet.tmpl" . }} and {{ template "_lib.tmpl" . }}`),
+		},
+		"child.tmpl": {
+			Data: []byte(`{{/* extends "_layout.tmpl" */}}ignored{{ define "body" }}child {{ .name }}{{ end }}`),
+		},
+		"child-default.tmpl": {
+			Data: []byte(`{{- /* extends "_layout.tmpl" */ -}}`),
+		},
+		"child-of-base.tmpl": {
+			Data: []byte(`{{/* extends "layouts/base.tmpl" */}}{{ define "title" }}Child{{ end }}`),
+		},
+		"front-matter.tmpl": {
+			Data: []byte("{{/*---\noutput: out.txt\n---*/}}\n{{/* extends \"_layout.tmpl\" */}}{{ define \"body\" }}fm{{ end }}"),
+		},
+		"one/deployment.tmpl": {
+			Data: []byte(`{{ template "one/_local.tmpl" . }}`),
+		},
+		"one/_local.tmpl": {
+			Data: []byte(`local {{ .name }}`),
+		},
+		"html.tmpl": {
+			Data: []byte("{{/*---\nengine: html\n---*/}}\n<p>{{ .name }} & {{ template \"_greet.tmpl\" . }}</p>"),
+		},
+		"text.tmpl": {
+			Data: []byte("{{/*---\nengine: text\n---*/}}\n<p>{{ .name }} & {{ template \"_greet.tmpl\" . }}</p>"),
+		},
+		"unknown-engine.tmpl": {
+			Data: []byte("{{/*---\nengine: jinja\n---*/}}\n"),
+		},
+		"broken.tmpl": {
+			Data: []byte(`{{ template "_greet.tmpl" . }}{{ if }}`),
+		},
+		"layouts/base.tmpl": {
+			Data: []byte(`# {{ block "title" . }}Base{{ end }}`),
+		},
+		"_greet.tmpl": {
+			Data: []byte(`Hi {{ .name }}`),
+		},
+		"_layout.tmpl": {
+			Data: []byte(`[{{ block "body" . }}default{{ end }}]`),
+		},
+	}
+	library := fstest.MapFS{
+		"_lib.tmpl": {
+			Data: []byte(`lib {{ .name }}`),
+		},
+		"_greet.tmpl": {
+			Data: []byte(`overridden by the template dir`),
+		},
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		want       string
+		wantErr    bool
+		wantErrMsg string
+		// The name passed to the template, foo if empty
+		data string
+	}{
+		{
+			name:   "plain template",
+			fields: fields{templates: templates},
+			args:   args{file: "plain.tmpl"},
+			want:   "Hello foo",
+		},
+		{
+			name:   "partials from template dir and library",
+			fields: fields{templates: templates, library: library},
+			args:   args{file: "partial.tmpl"},
+			want:   "Hi foo and lib foo",
+		},
+		{
+			name:   "layout with overridden block",
+			fields: fields{templates: templates},
+			args:   args{file: "child.tmpl"},
+			want:   "[child foo]",
+		},
+		{
+			name:   "layout with default block",
+			fields: fields{templates: templates},
+			args:   args{file: "child-default.tmpl"},
+			want:   "[default]",
+		},
+		{
+			name:   "layout outside the partials",
+			fields: fields{templates: templates},
+			args:   args{file: "child-of-base.tmpl"},
+			want:   "# Child",
+		},
+		{
+			name:   "front-matter with layout",
+			fields: fields{templates: templates},
+			args:   args{file: "front-matter.tmpl"},
+			want:   "[fm]",
+		},
+		{
+			name:   "partials in the dir of the template",
+			fields: fields{templates: templates},
+			args:   args{file: "one/deployment.tmpl"},
+			want:   "local foo",
+		},
+		{
+			name:   "html engine",
+			fields: fields{templates: templates},
+			args:   args{file: "html.tmpl"},
+			want:   "<p>&lt;b&gt; & Hi &lt;b&gt;</p>",
+			data:   "<b>",
+		},
+		{
+			name:   "html engine by default",
+			fields: fields{templates: templates},
+			args:   args{file: "plain.tmpl"},
+			want:   "Hello &lt;b&gt;",
+			data:   "<b>",
+		},
+		{
+			name:   "text engine",
+			fields: fields{templates: templates},
+			args:   args{file: "text.tmpl"},
+			want:   "<p><b> & Hi <b></p>",
+			data:   "<b>",
+		},
+		{
+			name:    "unknown engine",
+			fields:  fields{templates: templates},
+			args:    args{file: "unknown-engine.tmpl"},
+			wantErr: true,
+		},
+		{
+			name:       "error points at the file",
+			fields:     fields{templates: templates},
+			args:       args{file: "broken.tmpl"},
+			wantErr:    true,
+			wantErrMsg: "broken.tmpl:1",
+		},
+		{
+			name:    "missing template",
+			fields:  fields{templates: templates},
+			args:    args{file: "missing.tmpl"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := TemplateLoader{
+				templates: tt.fields.templates,
+				library:   tt.fields.library,
+				functions: template.FuncMap{},
+			}
+			tmpl, _, err := loader.Load(tt.args.file)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Errorf("Load() error = %v, want containing %v", err, tt.wantErrMsg)
+				}
+				return
+			}
+			buffer := bytes.Buffer{}
+			name := tt.data
+			if name == "" {
+				name = "foo"
+			}
+			if err := tmpl.Execute(&buffer, map[string]interface{}{"name": name}); err != nil {
+				t.Errorf("Execute() error = %v", err)
+				return
+			}
+			if got := buffer.String(); got != tt.want {
+				t.Errorf("Load() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
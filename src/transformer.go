@@ -2,7 +2,7 @@ package main
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"reflect"
 	"slices"
 	"strings"
@@ -16,6 +16,9 @@ func StringMapMapper(key string) Mapper {
 		if input == nil {
 			return nil
 		}
+		if valuer, ok := input.(Valuer); ok {
+			return valuer.Value(key)
+		}
 		mv := reflect.ValueOf(input)
 		kv := reflect.ValueOf(key)
 		v := mv.MapIndex(kv)
@@ -26,6 +29,28 @@ func StringMapMapper(key string) Mapper {
 	}
 }
 
+// MapPathMapper returns a mapper that follows a dot separated key path through nested maps,
+// e.g. "owner.team" extracts input["owner"]["team"].
+func MapPathMapper(keyPath string) Mapper {
+	keys := strings.Split(keyPath, ".")
+	return func(input interface{}) interface{} {
+		v := input
+		for _, key := range keys {
+			if _, ok := v.(Valuer); !ok && (v == nil || reflect.TypeOf(v).Kind() != reflect.Map) {
+				return nil
+			}
+			v = StringMapMapper(key)(v)
+		}
+		return v
+	}
+}
+
+// Valuer is a record that provides its values by key, e.g. a typed Feature. The mappers and predicates by key read
+// its values like the values of a map.
+type Valuer interface {
+	Value(key string) interface{}
+}
+
 type StringMapper func(input interface{}) string
 
 // IdentityMapper returns the input as a string.
@@ -33,9 +58,15 @@ func IdentityMapper(input interface{}) string {
 	return input.(string)
 }
 
+// MapValueStringMapper returns the map value indicated by key as a string, an empty string if it's not set.
 func MapValueStringMapper(key string) StringMapper {
+	mapper := StringMapMapper(key)
 	return func(input interface{}) string {
-		return input.(map[string]interface{})[key].(string)
+		v := mapper(input)
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
 	}
 }
 
@@ -43,17 +74,31 @@ type Predicate func(input interface{}) bool
 
 // MapValuePredicate returns a predicate that checks if the map value indicated by key is equal to the matchValue.
 func MapValuePredicate(key string, matchValue interface{}) Predicate {
+	mapper := StringMapMapper(key)
 	return func(input interface{}) bool {
-		if input == nil {
+		v := mapper(input)
+		if v == nil {
 			return false
 		}
-		mv := reflect.ValueOf(input)
-		kv := reflect.ValueOf(key)
-		v := mv.MapIndex(kv)
-		if v.Kind() == reflect.Invalid {
+		return reflect.DeepEqual(v, matchValue)
+	}
+}
+
+// MapValueContainsPredicate returns a predicate that checks if the map value indicated by key is a list containing
+// the matchValue.
+func MapValueContainsPredicate(key string, matchValue interface{}) Predicate {
+	return func(input interface{}) bool {
+		v := StringMapMapper(key)(input)
+		if v == nil || reflect.TypeOf(v).Kind() != reflect.Slice {
 			return false
 		}
-		return reflect.DeepEqual(v.Interface(), matchValue)
+		listV := reflect.ValueOf(v)
+		for i := 0; i < listV.Len(); i++ {
+			if reflect.DeepEqual(listV.Index(i).Interface(), matchValue) {
+				return true
+			}
+		}
+		return false
 	}
 }
 
@@ -61,6 +106,19 @@ type Transformer interface {
 	Transform(input interface{}) (interface{}, error)
 }
 
+var (
+	_ Transformer = &ListToMapTransformer{}
+	_ Transformer = ListMappingTransformer{}
+	_ Transformer = ListExpandTransformer{}
+	_ Transformer = ListFilterTransformer{}
+	_ Transformer = ListStringSortTransformer{}
+	_ Transformer = GroupByTransformer{}
+	_ Transformer = JoinTransformer{}
+	_ Transformer = ListDistinctTransformer{}
+	_ Transformer = ListSetTransformer{}
+	_ Transformer = FeatureDecodeTransformer{}
+)
+
 // ListToMapTransformer is a transformer that converts a list to a map using the specified key/value mapper.
 type ListToMapTransformer struct {
 	keyMapper   Mapper
@@ -85,28 +143,113 @@ func (config *ListToMapTransformer) Transform(input interface{}) (interface{}, e
 	return m, nil
 }
 
+// The maximum number of mappings applied to one element when chaining
+const maxMappingChain = 100
+
 type ListMappingTransformer struct {
+	// The mapping rules by key, see MappingRule for the key syntax
 	mapping map[string]string
+	// Apply the mapping again on the mapped value until no rule changes it
+	chain bool
+	// Optional, receives the unused keys, conflicts and dropped inputs
+	report *MappingReport
+	// Optional, collects the warnings
+	warnings *Warnings
 }
 
 // Transform transforms the input list by mapping the elements to the values in the mapping.
-// If the element is not found in the mapping, original value is used.
-func (config ListMappingTransformer) Transform(input interface{}) (records []interface{}, err error) {
+// If the element is not found in the mapping, original value is used. Elements mapped to an empty value are dropped.
+// Exact match keys take precedence over the patterns, when several patterns match the first key in order is used.
+func (config ListMappingTransformer) Transform(input interface{}) (interface{}, error) {
 	if input == nil {
 		return nil, errors.New("ListMappingTransformer: Input is nil")
 	}
 	if reflect.TypeOf(input).Kind() != reflect.Slice {
 		return nil, errors.New("ListMappingTransformer: Input is not a list")
 	}
+	exact, patterns, err := compileMappingRules(config.mapping)
+	if err != nil {
+		return nil, fmt.Errorf("ListMappingTransformer: %w", err)
+	}
+	report := MappingReport{}
+	used := make(map[string]bool)
+	conflicts := make(map[string]bool)
+	lookup := func(name string) (string, bool) {
+		if rule, ok := exact[name]; ok {
+			used[rule.key] = true
+			return rule.replacement, true
+		}
+		var value string
+		keys := make([]string, 0)
+		for _, rule := range patterns {
+			if v, ok := rule.Apply(name); ok {
+				if len(keys) == 0 {
+					value = v
+				}
+				keys = append(keys, rule.key)
+				used[rule.key] = true
+			}
+		}
+		if len(keys) > 1 && !conflicts[name] {
+			conflicts[name] = true
+			report.Conflicts = append(report.Conflicts, MappingConflict{Input: name, Keys: keys})
+		}
+		return value, len(keys) > 0
+	}
+
 	listV := reflect.ValueOf(input)
-	records = make([]interface{}, 0)
+	records := make([]interface{}, 0)
+	inputs := make([]string, 0, listV.Len())
 	for i := 0; i < listV.Len(); i++ {
 		el := listV.Index(i).String()
-		if val, ok := config.mapping[el]; ok {
-			records = append(records, val)
-		} else {
-			records = append(records, el)
+		inputs = append(inputs, el)
+		name := el
+		path := []string{name}
+		for {
+			next, ok := lookup(name)
+			if !ok || next == name {
+				break
+			}
+			if slices.Contains(path, next) {
+				return nil, fmt.Errorf("ListMappingTransformer: Mapping loop %s -> %s", strings.Join(path, " -> "), next)
+			}
+			if len(path) > maxMappingChain {
+				return nil, fmt.Errorf("ListMappingTransformer: Mapping of '%s' does not reach a fixed point", el)
+			}
+			name = next
+			path = append(path, name)
+			if !config.chain || name == "" {
+				break
+			}
+		}
+		if name == "" {
+			report.Dropped = append(report.Dropped, el)
+			continue
+		}
+		if len(path) > 1 {
+			if report.Renamed == nil {
+				report.Renamed = make(map[string][]string)
+			}
+			report.Renamed[el] = path
 		}
+		records = append(records, name)
+	}
+
+	// report mapping results
+	for key := range config.mapping {
+		if !used[key] {
+			report.UnusedKeys = append(report.UnusedKeys, key)
+		}
+	}
+	slices.Sort(report.UnusedKeys)
+	for _, key := range report.UnusedKeys {
+		config.warnings.Warnf(WarnUnusedMappingKey, "Mapping key '%s' not used in mapping transformer%s", key, didYouMean(key, inputs))
+	}
+	for _, conflict := range report.Conflicts {
+		config.warnings.Warnf(WarnMappingConflict, "Input '%s' matches mapping keys %v, '%s' used", conflict.Input, conflict.Keys, conflict.Keys[0])
+	}
+	if config.report != nil {
+		*config.report = report
 	}
 	return records, nil
 }
@@ -116,20 +259,26 @@ type ListExpandTransformer struct {
 	keyMapper StringMapper
 	// Keep the original key name into the config struct. The config struct must be a map
 	keepKeyName bool
+	// Optional, collects the warnings
+	warnings *Warnings
 }
 
 // Transform transforms the input list by expanding the elements to the values in the data.
-func (config ListExpandTransformer) Transform(input interface{}) (record []interface{}, err error) {
+func (config ListExpandTransformer) Transform(input interface{}) (interface{}, error) {
 	if input == nil {
 		return nil, errors.New("ListExpandTransformer: Input is nil")
 	}
 	if reflect.TypeOf(input).Kind() != reflect.Slice {
 		return nil, errors.New("ListExpandTransformer: Input is not a list")
 	}
+	// the config keys used, by the key of the elements
 	bookkeeping := make(map[interface{}]bool)
-	for k, _ := range config.dataByKey {
+	keys := make([]string, 0, len(config.dataByKey))
+	for k := range config.dataByKey {
 		bookkeeping[k] = false
+		keys = append(keys, fmt.Sprint(k))
 	}
+	slices.Sort(keys)
 	listV := reflect.ValueOf(input)
 	records := make([]interface{}, 0)
 	for i := 0; i < listV.Len(); i++ {
@@ -140,16 +289,16 @@ func (config ListExpandTransformer) Transform(input interface{}) (record []inter
 				reflect.ValueOf(val).SetMapIndex(reflect.ValueOf("Name"), reflect.ValueOf(keyName))
 			}
 			records = append(records, val)
-			bookkeeping[el] = true
+			bookkeeping[keyName] = true
 		} else {
-			log.Printf("Input key '%s' not found in data", el)
+			config.warnings.Warnf(WarnMissingFeature, "Input key '%s' not found in data%s", keyName, didYouMean(keyName, keys))
 		}
 	}
 
-	// report bookkeeping results
-	for k, v := range bookkeeping {
-		if v == false {
-			log.Printf("Config key '%s' not used in expand transformer", k)
+	// report bookkeeping results, in key order
+	for _, k := range keys {
+		if !bookkeeping[k] {
+			config.warnings.Warnf(WarnUnusedConfigKey, "Config key '%s' not used in expand transformer", k)
 		}
 	}
 	return records, nil
@@ -159,7 +308,7 @@ type ListFilterTransformer struct {
 	predicate Predicate
 }
 
-func (config ListFilterTransformer) Transform(input interface{}) (records []interface{}, err error) {
+func (config ListFilterTransformer) Transform(input interface{}) (interface{}, error) {
 	if input == nil {
 		return nil, errors.New("ListFilterTransformer: Input is nil")
 	}
@@ -167,7 +316,7 @@ func (config ListFilterTransformer) Transform(input interface{}) (records []inte
 		return nil, errors.New("ListFilterTransformer: Input is not a list")
 	}
 	listV := reflect.ValueOf(input)
-	records = make([]interface{}, 0)
+	records := make([]interface{}, 0)
 	for i := 0; i < listV.Len(); i++ {
 		el := listV.Index(i).Interface()
 		if config.predicate(el) {
@@ -181,21 +330,66 @@ type ListStringSortTransformer struct {
 	mapper StringMapper
 }
 
-func (config ListStringSortTransformer) Transform(input interface{}) ([]interface{}, error) {
+// Transform returns a copy of the list stably sorted by the string of each element.
+func (config ListStringSortTransformer) Transform(input interface{}) (interface{}, error) {
 	if input == nil {
 		return nil, errors.New("ListSortTransformer: Input is nil")
 	}
 	if reflect.TypeOf(input).Kind() != reflect.Slice {
 		return nil, errors.New("ListSortTransformer: Input is not a list")
 	}
-	list := input.([]interface{})
 	listV := reflect.ValueOf(input)
-	for i := 0; i < listV.Len(); i++ {
-		slices.SortStableFunc(list, func(i, j interface{}) int {
-			a := config.mapper(i)
-			b := config.mapper(j)
-			return strings.Compare(a, b)
-		})
+	list := make([]interface{}, listV.Len())
+	for i := range list {
+		list[i] = listV.Index(i).Interface()
 	}
+	slices.SortStableFunc(list, func(i, j interface{}) int {
+		a := config.mapper(i)
+		b := config.mapper(j)
+		return strings.Compare(a, b)
+	})
 	return list, nil
 }
+
+// GroupByTransformer partitions a list into a map of lists, keyed by the value keyMapper returns for each element.
+// The relative order of the elements is kept inside each group.
+type GroupByTransformer struct {
+	keyMapper Mapper
+	// When the key is a list, put the element into the group of every item in the list
+	multiValue bool
+	// Optional, collects the warnings
+	warnings *Warnings
+}
+
+// Transform transforms the input list into map[string][]interface{}. Elements without a key are dropped.
+func (config GroupByTransformer) Transform(input interface{}) (interface{}, error) {
+	if input == nil {
+		return nil, errors.New("GroupByTransformer: Input is nil")
+	}
+	if reflect.TypeOf(input).Kind() != reflect.Slice {
+		return nil, errors.New("GroupByTransformer: Input is not a list")
+	}
+	groups := make(map[string][]interface{})
+	listV := reflect.ValueOf(input)
+	for i := 0; i < listV.Len(); i++ {
+		el := listV.Index(i).Interface()
+		key := config.keyMapper(el)
+		if key == nil {
+			config.warnings.Warnf(WarnMissingGroupKey, "Element %d has no group key, dropped by group by transformer", i)
+			continue
+		}
+		keys := []interface{}{key}
+		if config.multiValue && reflect.TypeOf(key).Kind() == reflect.Slice {
+			keyV := reflect.ValueOf(key)
+			keys = make([]interface{}, keyV.Len())
+			for j := range keys {
+				keys[j] = keyV.Index(j).Interface()
+			}
+		}
+		for _, k := range keys {
+			name := fmt.Sprint(k)
+			groups[name] = append(groups[name], el)
+		}
+	}
+	return groups, nil
+}
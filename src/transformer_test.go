@@ -1,6 +1,8 @@
 package main
 
 import (
+	"io"
+	"log/slog"
 	"reflect"
 	"testing"
 )
@@ -99,6 +101,7 @@ func TestStringMapMapper(t *testing.T) {
 func TestListMappingTransformer_Transform(t *testing.T) {
 	type fields struct {
 		mapping map[string]string
+		chain   bool
 	}
 	type args struct {
 		input interface{}
@@ -127,25 +130,138 @@ func TestListMappingTransformer_Transform(t *testing.T) {
 				"0", "one", "2",
 			},
 		},
-		// TODO: Add test cases.
+		{
+			name: "patterns and drop",
+			fields: fields{
+				mapping: map[string]string{
+					"legacy-*":         "$1",
+					"/^team-(.+)$/":    "Team$1",
+					"/^(.*)-removed$/": "",
+				},
+			},
+			args: args{
+				input: []string{
+					"legacy-foo", "team-bar", "baz-removed", "qux",
+				},
+			},
+			wantRecords: []interface{}{
+				"foo", "Teambar", "qux",
+			},
+		},
+		{
+			name: "exact match wins over patterns",
+			fields: fields{
+				mapping: map[string]string{
+					"legacy-foo": "Foo",
+					"legacy-*":   "$1",
+				},
+			},
+			args: args{
+				input: []string{
+					"legacy-foo", "legacy-bar",
+				},
+			},
+			wantRecords: []interface{}{
+				"Foo", "bar",
+			},
+		},
+		{
+			name: "chain to fixed point",
+			fields: fields{
+				mapping: map[string]string{
+					"a":          "b",
+					"b":          "c",
+					"/^(.*)-x$/": "$1",
+				},
+				chain: true,
+			},
+			args: args{
+				input: []string{
+					"a", "foo-x-x", "c",
+				},
+			},
+			wantRecords: []interface{}{
+				"c", "foo", "c",
+			},
+		},
+		{
+			name: "chain loop",
+			fields: fields{
+				mapping: map[string]string{
+					"a": "b",
+					"b": "a",
+				},
+				chain: true,
+			},
+			args: args{
+				input: []string{
+					"a",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid regex",
+			fields: fields{
+				mapping: map[string]string{
+					"/(/": "a",
+				},
+			},
+			args: args{
+				input: []string{
+					"a",
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			config := ListMappingTransformer{
 				mapping: tt.fields.mapping,
+				chain:   tt.fields.chain,
 			}
 			gotRecords, err := config.Transform(tt.args.input)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(gotRecords, tt.wantRecords) {
+			if !tt.wantErr && !reflect.DeepEqual(gotRecords, tt.wantRecords) {
 				t.Errorf("Transform() gotRecords = %v, want %v", gotRecords, tt.wantRecords)
 			}
 		})
 	}
 }
 
+func TestListMappingTransformer_Report(t *testing.T) {
+	report := MappingReport{}
+	config := ListMappingTransformer{
+		mapping: map[string]string{
+			"foo-*":     "Foo",
+			"/^foo-b/":  "FooB",
+			"unused":    "nothing",
+			"removed":   "",
+			"/^never$/": "nothing",
+		},
+		report: &report,
+	}
+	_, err := config.Transform([]string{"foo-bar", "foo-bar", "removed"})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	want := MappingReport{
+		UnusedKeys: []string{"/^never$/", "unused"},
+		Conflicts: []MappingConflict{
+			{Input: "foo-bar", Keys: []string{"/^foo-b/", "foo-*"}},
+		},
+		Dropped: []string{"removed"},
+		Renamed: map[string][]string{"foo-bar": {"foo-bar", "FooB"}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Transform() report = %v, want %v", report, want)
+	}
+}
+
 func TestListExpandTransformer_Transform(t *testing.T) {
 	type fields struct {
 		dataByKey   map[interface{}]interface{}
@@ -238,6 +354,36 @@ func TestListExpandTransformer_Transform(t *testing.T) {
 	}
 }
 
+func TestListExpandTransformer_Warnings(t *testing.T) {
+	dataByKey := map[interface{}]interface{}{
+		"foo":    map[interface{}]interface{}{"val": 10},
+		"bar":    map[interface{}]interface{}{"val": 15},
+		"foobar": map[interface{}]interface{}{"val": 20},
+	}
+	warnings := NewWarnings(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	config := ListExpandTransformer{
+		dataByKey: dataByKey,
+		// the elements are not the keys, the config keys used are the keys of the elements
+		keyMapper: MapValueStringMapper("name"),
+		warnings:  warnings,
+	}
+	input := []interface{}{
+		map[string]interface{}{"name": "bar"},
+		map[string]interface{}{"name": "fooo"},
+	}
+	if _, err := config.Transform(input); err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	want := []Warning{
+		{Type: WarnMissingFeature, Message: "Input key 'fooo' not found in data, did you mean 'foo'?"},
+		{Type: WarnUnusedConfigKey, Message: "Config key 'foo' not used in expand transformer"},
+		{Type: WarnUnusedConfigKey, Message: "Config key 'foobar' not used in expand transformer"},
+	}
+	if got := warnings.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+}
+
 var testData = []map[string]interface{}{
 	{"featureSet": "one", "name": "foo"},
 	{"featureSet": "two", "name": "bar"},
@@ -342,7 +488,7 @@ func TestListStringSortTransformer_Transform(t *testing.T) {
 func TestMapValueStringMapper(t *testing.T) {
 	type args struct {
 		key  string
-		data map[string]interface{}
+		data interface{}
 	}
 	tests := []struct {
 		name string
@@ -359,7 +505,30 @@ func TestMapValueStringMapper(t *testing.T) {
 			},
 			want: "A01",
 		},
-		// TODO: Add test cases.
+		{
+			name: "Number",
+			args: args{
+				key:  "priority",
+				data: map[string]interface{}{"priority": 10},
+			},
+			want: "10",
+		},
+		{
+			name: "Not set",
+			args: args{
+				key:  "priority",
+				data: map[interface{}]interface{}{"feature-set": "one"},
+			},
+			want: "",
+		},
+		{
+			name: "Typed feature",
+			args: args{
+				key:  "priority",
+				data: Feature{Name: "Foo", Priority: "A01"},
+			},
+			want: "A01",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -370,3 +539,178 @@ func TestMapValueStringMapper(t *testing.T) {
 		})
 	}
 }
+
+func TestMapPathMapper(t *testing.T) {
+	type args struct {
+		keyPath string
+	}
+	tests := []struct {
+		name string
+		args args
+		want interface{}
+	}{
+		{
+			name: "Happy path",
+			args: args{
+				keyPath: "owner.team",
+			},
+			want: "core",
+		},
+		{
+			name: "Single key",
+			args: args{
+				keyPath: "name",
+			},
+			want: "foo",
+		},
+		{
+			name: "Not found",
+			args: args{
+				keyPath: "owner.not-found",
+			},
+			want: nil,
+		},
+		{
+			name: "Not a map",
+			args: args{
+				keyPath: "name.team",
+			},
+			want: nil,
+		},
+	}
+	testData := map[string]interface{}{
+		"name": "foo",
+		"owner": map[string]interface{}{
+			"team": "core",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := MapPathMapper(tt.args.keyPath)
+			if got := mapper(testData); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MapPathMapper() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByTransformer_Transform(t *testing.T) {
+	type fields struct {
+		keyMapper  Mapper
+		multiValue bool
+	}
+	type args struct {
+		input interface{}
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "normal flow",
+			fields: fields{
+				keyMapper: StringMapMapper("featureSet"),
+			},
+			args: args{
+				input: testData,
+			},
+			want: map[string][]interface{}{
+				"one": {
+					map[string]interface{}{"featureSet": "one", "name": "foo"},
+					map[string]interface{}{"featureSet": "one", "name": "baz"},
+				},
+				"two": {
+					map[string]interface{}{"featureSet": "two", "name": "bar"},
+				},
+			},
+		},
+		{
+			name: "multi value",
+			fields: fields{
+				keyMapper:  StringMapMapper("featureSet"),
+				multiValue: true,
+			},
+			args: args{
+				input: []interface{}{
+					map[string]interface{}{"featureSet": []interface{}{"one", "two"}, "name": "foo"},
+					map[string]interface{}{"featureSet": "two", "name": "bar"},
+					map[string]interface{}{"name": "baz"},
+				},
+			},
+			want: map[string][]interface{}{
+				"one": {
+					map[string]interface{}{"featureSet": []interface{}{"one", "two"}, "name": "foo"},
+				},
+				"two": {
+					map[string]interface{}{"featureSet": []interface{}{"one", "two"}, "name": "foo"},
+					map[string]interface{}{"featureSet": "two", "name": "bar"},
+				},
+			},
+		},
+		{
+			name: "not a list",
+			fields: fields{
+				keyMapper: StringMapMapper("featureSet"),
+			},
+			args: args{
+				input: "foo",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := GroupByTransformer{
+				keyMapper:  tt.fields.keyMapper,
+				multiValue: tt.fields.multiValue,
+			}
+			got, err := config.Transform(tt.args.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapValueContainsPredicate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input interface{}
+		want  bool
+	}{
+		{
+			name:  "list contains",
+			input: map[string]interface{}{"feature-set": []interface{}{"one", "two"}},
+			want:  true,
+		},
+		{
+			name:  "list does not contain",
+			input: map[string]interface{}{"feature-set": []interface{}{"one"}},
+			want:  false,
+		},
+		{
+			name:  "not a list",
+			input: map[string]interface{}{"feature-set": "two"},
+			want:  false,
+		},
+		{
+			name:  "nil",
+			input: nil,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapValueContainsPredicate("feature-set", "two")(tt.input); got != tt.want {
+				t.Errorf("MapValueContainsPredicate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// TypedTransformer is the type-safe counterpart of Transformer, the types of the input and the output are checked at
+// compile time.
+type TypedTransformer[In, Out any] interface {
+	Apply(input In) (Out, error)
+}
+
+// Func is a function used as a TypedTransformer. It's an untyped Transformer too, so it can be a step of the pipeline:
+// the input is converted to In, see Untyped.
+type Func[In, Out any] func(input In) (Out, error)
+
+func (f Func[In, Out]) Apply(input In) (Out, error) {
+	return f(input)
+}
+
+func (f Func[In, Out]) Transform(input interface{}) (interface{}, error) {
+	typed, err := convertValue[In](input)
+	if err != nil {
+		return nil, fmt.Errorf("Func: %w", err)
+	}
+	return f(typed)
+}
+
+// Then chains two transformers, the output of the first is the input of the second.
+func Then[A, B, C any](first TypedTransformer[A, B], second TypedTransformer[B, C]) Func[A, C] {
+	return func(input A) (C, error) {
+		b, err := first.Apply(input)
+		if err != nil {
+			var zero C
+			return zero, err
+		}
+		return second.Apply(b)
+	}
+}
+
+// Map converts every element of the list.
+func Map[In, Out any](mapper func(In) Out) Func[[]In, []Out] {
+	return func(input []In) ([]Out, error) {
+		records := make([]Out, len(input))
+		for i, el := range input {
+			records[i] = mapper(el)
+		}
+		return records, nil
+	}
+}
+
+// Filter keeps the elements of the list matching the predicate.
+func Filter[T any](predicate func(T) bool) Func[[]T, []T] {
+	return func(input []T) ([]T, error) {
+		records := make([]T, 0, len(input))
+		for _, el := range input {
+			if predicate(el) {
+				records = append(records, el)
+			}
+		}
+		return records, nil
+	}
+}
+
+// SortBy returns a copy of the list stably sorted by the key of each element.
+func SortBy[T any, K cmp.Ordered](key func(T) K) Func[[]T, []T] {
+	return func(input []T) ([]T, error) {
+		records := slices.Clone(input)
+		slices.SortStableFunc(records, func(a, b T) int {
+			return cmp.Compare(key(a), key(b))
+		})
+		return records, nil
+	}
+}
+
+// GroupBy partitions the list by the keys of each element, an element with several keys is in several groups and an
+// element without keys in none. The order of the elements is kept inside each group.
+func GroupBy[T any, K comparable](keys func(T) []K) Func[[]T, map[K][]T] {
+	return func(input []T) (map[K][]T, error) {
+		groups := make(map[K][]T)
+		for _, el := range input {
+			for _, k := range keys(el) {
+				groups[k] = append(groups[k], el)
+			}
+		}
+		return groups, nil
+	}
+}
+
+// Expand replaces every element of the list by the elements the expander returns for it.
+func Expand[In, Out any](expander func(In) ([]Out, error)) Func[[]In, []Out] {
+	return func(input []In) ([]Out, error) {
+		records := make([]Out, 0, len(input))
+		for i, el := range input {
+			expanded, err := expander(el)
+			if err != nil {
+				return nil, fmt.Errorf("Expand: element %d: %w", i, err)
+			}
+			records = append(records, expanded...)
+		}
+		return records, nil
+	}
+}
+
+// Joined is a row of Join, Matched is false when Right is the zero value because no right element matched.
+type Joined[L, R any] struct {
+	Left    L
+	Right   R
+	Matched bool
+}
+
+// Join joins the list with the right elements of the same key, like JoinTransformer does for untyped elements.
+// InnerJoin and LeftJoin return a row per match, AntiJoin the left elements without a match.
+func Join[L, R any, K comparable](right []R, leftKey func(L) K, rightKey func(R) K, joinType JoinType) Func[[]L, []Joined[L, R]] {
+	return func(input []L) ([]Joined[L, R], error) {
+		rightByKey := make(map[K][]R)
+		for _, r := range right {
+			k := rightKey(r)
+			rightByKey[k] = append(rightByKey[k], r)
+		}
+		records := make([]Joined[L, R], 0, len(input))
+		for _, l := range input {
+			matches := rightByKey[leftKey(l)]
+			switch {
+			case len(matches) == 0 && joinType != InnerJoin:
+				records = append(records, Joined[L, R]{Left: l})
+			case len(matches) > 0 && joinType != AntiJoin:
+				for _, r := range matches {
+					records = append(records, Joined[L, R]{Left: l, Right: r, Matched: true})
+				}
+			}
+		}
+		return records, nil
+	}
+}
+
+// Untyped adapts a typed transformer to a step of the untyped pipeline, see Func.Transform.
+func Untyped[In, Out any](transformer TypedTransformer[In, Out]) Transformer {
+	return Func[In, Out](transformer.Apply)
+}
+
+// Typed adapts an untyped transformer to a typed one, the output is converted to Out.
+// e.g. Typed[[]string, []string](ListMappingTransformer{mapping: mapping})
+func Typed[In, Out any](transformer Transformer) Func[In, Out] {
+	return func(input In) (Out, error) {
+		output, err := transformer.Transform(input)
+		if err != nil {
+			var zero Out
+			return zero, err
+		}
+		return convertValue[Out](output)
+	}
+}
+
+// convertValue converts the untyped value to T. The lists and the maps are converted element by element, so a
+// []interface{} holding strings is converted to []string.
+func convertValue[T any](value interface{}) (T, error) {
+	if typed, ok := value.(T); ok {
+		return typed, nil
+	}
+	var zero T
+	converted, err := convertReflect(reflect.ValueOf(value), reflect.TypeOf(&zero).Elem())
+	if err != nil {
+		return zero, err
+	}
+	return converted.Interface().(T), nil
+}
+
+func convertReflect(value reflect.Value, target reflect.Type) (reflect.Value, error) {
+	for value.IsValid() && value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	result := reflect.New(target).Elem()
+	if !value.IsValid() {
+		return result, nil
+	}
+	if value.Type().AssignableTo(target) {
+		result.Set(value)
+		return result, nil
+	}
+	switch {
+	case target.Kind() == reflect.Slice && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array):
+		result = reflect.MakeSlice(target, value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			el, err := convertReflect(value.Index(i), target.Elem())
+			if err != nil {
+				return result, fmt.Errorf("element %d: %w", i, err)
+			}
+			result.Index(i).Set(el)
+		}
+		return result, nil
+	case target.Kind() == reflect.Map && value.Kind() == reflect.Map:
+		result = reflect.MakeMapWithSize(target, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			k, err := convertReflect(iter.Key(), target.Key())
+			if err != nil {
+				return result, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			v, err := convertReflect(iter.Value(), target.Elem())
+			if err != nil {
+				return result, fmt.Errorf("value of %v: %w", iter.Key(), err)
+			}
+			result.SetMapIndex(k, v)
+		}
+		return result, nil
+	}
+	return result, fmt.Errorf("%s is not %s", value.Type(), target)
+}
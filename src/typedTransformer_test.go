@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testFeature struct {
+	name     string
+	priority int
+	sets     []string
+}
+
+var testFeatures = []testFeature{
+	{name: "Foo", priority: 2, sets: []string{"one"}},
+	{name: "Bar", priority: 1, sets: []string{"one", "two"}},
+	{name: "Baz", priority: 3},
+}
+
+func TestTypedCombinators(t *testing.T) {
+	name := func(f testFeature) string { return f.name }
+	tests := []struct {
+		name        string
+		transformer TypedTransformer[[]testFeature, []string]
+		want        []string
+	}{
+		{name: "map", transformer: Map(name), want: []string{"Foo", "Bar", "Baz"}},
+		{
+			name:        "filter",
+			transformer: Then[[]testFeature, []testFeature, []string](Filter(func(f testFeature) bool { return len(f.sets) > 0 }), Map(name)),
+			want:        []string{"Foo", "Bar"},
+		},
+		{
+			name:        "sort",
+			transformer: Then[[]testFeature, []testFeature, []string](SortBy(func(f testFeature) int { return f.priority }), Map(name)),
+			want:        []string{"Bar", "Foo", "Baz"},
+		},
+		{
+			name: "expand",
+			transformer: Expand(func(f testFeature) ([]string, error) {
+				return f.sets, nil
+			}),
+			want: []string{"one", "one", "two"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.transformer.Apply(testFeatures)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	got, err := GroupBy(func(f testFeature) []string { return f.sets }).Apply(testFeatures)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	want := map[string][]testFeature{
+		"one": {testFeatures[0], testFeatures[1]},
+		"two": {testFeatures[1]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() got = %v, want %v", got, want)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	owners := [][2]string{{"Foo", "team-a"}, {"Foo", "team-b"}, {"Qux", "team-c"}}
+	leftKey := func(f testFeature) string { return f.name }
+	rightKey := func(owner [2]string) string { return owner[0] }
+	tests := []struct {
+		name     string
+		joinType JoinType
+		want     []string
+	}{
+		{name: "inner", joinType: InnerJoin, want: []string{"Foo:team-a", "Foo:team-b"}},
+		{name: "left", joinType: LeftJoin, want: []string{"Foo:team-a", "Foo:team-b", "Bar:", "Baz:"}},
+		{name: "anti", joinType: AntiJoin, want: []string{"Bar:", "Baz:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			join := Join(owners, leftKey, rightKey, tt.joinType)
+			format := Map(func(row Joined[testFeature, [2]string]) string {
+				if !row.Matched {
+					return row.Left.name + ":"
+				}
+				return row.Left.name + ":" + row.Right[1]
+			})
+			got, err := Then[[]testFeature, []Joined[testFeature, [2]string], []string](join, format).Apply(testFeatures)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThen_Error(t *testing.T) {
+	failing := Expand(func(s string) ([]string, error) {
+		if s == "bad" {
+			return nil, errors.New("bad element")
+		}
+		return []string{s}, nil
+	})
+	_, err := Then[[]string, []string, []string](failing, Map(strings.ToUpper)).Apply([]string{"ok", "bad"})
+	if err == nil || !strings.Contains(err.Error(), "element 1") {
+		t.Errorf("Apply() error = %v, want the error of element 1", err)
+	}
+}
+
+func TestUntyped(t *testing.T) {
+	upper := Untyped[[]string, []string](Map(strings.ToUpper))
+	tests := []struct {
+		name    string
+		input   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "typed input", input: []string{"a"}, want: []string{"A"}},
+		{name: "untyped list", input: []interface{}{"a", "b"}, want: []string{"A", "B"}},
+		{name: "nil", input: nil, want: []string{}},
+		{name: "wrong element", input: []interface{}{"a", 1}, wantErr: true},
+		{name: "not a list", input: "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := upper.Transform(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transform() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTyped(t *testing.T) {
+	mapping := Typed[[]string, []string](ListMappingTransformer{mapping: map[string]string{"foo": "bar"}})
+	got, err := mapping.Apply([]string{"foo", "baz"})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := []string{"bar", "baz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() got = %v, want %v", got, want)
+	}
+
+	groups := Typed[[]interface{}, map[string][]map[string]interface{}](GroupByTransformer{keyMapper: StringMapMapper("in")})
+	grouped, err := groups.Apply([]interface{}{map[string]interface{}{"in": "A"}})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(grouped["A"]) != 1 || grouped["A"][0]["in"] != "A" {
+		t.Errorf("Apply() got = %v", grouped)
+	}
+
+	if _, err := Typed[[]string, []int](ListMappingTransformer{mapping: map[string]string{}}).Apply([]string{"a"}); err == nil {
+		t.Errorf("Apply() converted strings to int")
+	}
+}
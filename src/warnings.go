@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// The types of the warnings, the report tells them apart by type
+const (
+	WarnRecordLength     = "record-length"
+	WarnRemovedNotListed = "removed-feature-not-listed"
+	WarnUnmatchedLeft    = "unmatched-left"
+	WarnUnmatchedRight   = "unmatched-right"
+	WarnNoTemplate       = "feature-set-without-template"
+	WarnUnusedMappingKey = "unused-mapping-key"
+	WarnMappingConflict  = "mapping-conflict"
+	WarnMissingFeature   = "missing-feature"
+	WarnUnusedConfigKey  = "unused-config-key"
+	WarnMissingGroupKey  = "missing-group-key"
+)
+
+// The warning types, in the order of the constants
+var warningTypes = []string{
+	WarnRecordLength, WarnRemovedNotListed, WarnUnmatchedLeft, WarnUnmatchedRight, WarnNoTemplate,
+	WarnUnusedMappingKey, WarnMappingConflict, WarnMissingFeature, WarnUnusedConfigKey, WarnMissingGroupKey,
+}
+
+// The key of the warning policies setting the policy of the types without one
+const allWarnings = "all"
+
+// WarningPolicy decides what a warning does.
+type WarningPolicy int
+
+const (
+	// LogWarning logs and collects the warning, the run goes on.
+	LogWarning WarningPolicy = iota
+	// IgnoreWarning drops the warning.
+	IgnoreWarning
+	// FailOnWarning logs and collects the warning as an error, the run fails after the step.
+	FailOnWarning
+)
+
+// ParseWarningPolicy converts the policy name (ignore, warn, error) to WarningPolicy.
+func ParseWarningPolicy(name string) (WarningPolicy, error) {
+	switch name {
+	case "warn", "":
+		return LogWarning, nil
+	case "ignore":
+		return IgnoreWarning, nil
+	case "error":
+		return FailOnWarning, nil
+	}
+	return LogWarning, fmt.Errorf("unknown warning policy '%s', use ignore, warn or error", name)
+}
+
+// ParseWarningPolicies converts the type=policy entries to the policies by type, the type all sets the policy of the
+// types without one.
+func ParseWarningPolicies(entries []string) (map[string]WarningPolicy, error) {
+	policies := make(map[string]WarningPolicy, len(entries))
+	for _, entry := range entries {
+		warningType, name, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("warning policy '%s' is not type=policy", entry)
+		}
+		warningType = strings.TrimSpace(warningType)
+		if warningType != allWarnings && !slices.Contains(warningTypes, warningType) {
+			return nil, fmt.Errorf("unknown warning type '%s'%s", warningType, didYouMean(warningType, warningTypes))
+		}
+		policy, err := ParseWarningPolicy(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		policies[warningType] = policy
+	}
+	return policies, nil
+}
+
+// Warning is a finding of a step that doesn't stop the run, e.g. a mapping key matching no feature.
+type Warning struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// True if the policy of the type makes it an error
+	Error bool `json:"error,omitempty"`
+}
+
+// Warnings collects the warnings of a run, they are logged as they are added. A nil collector only logs, to the default
+// logger. It's safe for concurrent use, the feature sets are rendered concurrently.
+type Warnings struct {
+	mu     sync.Mutex
+	list   []Warning
+	logger *slog.Logger
+	// The policy by warning type, the types without are logged
+	policies map[string]WarningPolicy
+}
+
+// NewWarnings returns a collector logging to the logger, the default one if nil, applying the policies by type.
+func NewWarnings(logger *slog.Logger, policies map[string]WarningPolicy) *Warnings {
+	return &Warnings{logger: logger, policies: policies}
+}
+
+// Warnf logs and collects a warning of the type, unless its policy ignores it.
+func (warnings *Warnings) Warnf(warningType string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if warnings == nil {
+		slog.Warn(message, "type", warningType)
+		return
+	}
+	policy := warnings.policy(warningType)
+	switch policy {
+	case IgnoreWarning:
+		return
+	case FailOnWarning:
+		loggerOrDefault(warnings.logger).Error(message, "type", warningType)
+	default:
+		loggerOrDefault(warnings.logger).Warn(message, "type", warningType)
+	}
+	warnings.mu.Lock()
+	defer warnings.mu.Unlock()
+	warnings.list = append(warnings.list, Warning{Type: warningType, Message: message, Error: policy == FailOnWarning})
+}
+
+func (warnings *Warnings) policy(warningType string) WarningPolicy {
+	if policy, ok := warnings.policies[warningType]; ok {
+		return policy
+	}
+	return warnings.policies[allWarnings]
+}
+
+// List returns the warnings in the order they were added.
+func (warnings *Warnings) List() []Warning {
+	if warnings == nil {
+		return nil
+	}
+	warnings.mu.Lock()
+	defer warnings.mu.Unlock()
+	return slices.Clone(warnings.list)
+}
+
+// Err returns the warnings whose policy is error, nil if there are none.
+func (warnings *Warnings) Err() error {
+	failures := make([]error, 0)
+	for _, warning := range warnings.List() {
+		if warning.Error {
+			failures = append(failures, fmt.Errorf("%s: %s", warning.Type, warning.Message))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// ignoredWarnings returns a collector dropping every warning, for a step repeating the work of another one that already
+// reports its warnings.
+func ignoredWarnings() *Warnings {
+	return NewWarnings(nil, map[string]WarningPolicy{allWarnings: IgnoreWarning})
+}
+
+// contextWarnings returns the collector of the run, nil when the warnings are not collected.
+func contextWarnings(context map[string]interface{}) *Warnings {
+	warnings, _ := context["warnings"].(*Warnings)
+	return warnings
+}
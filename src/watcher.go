@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Watcher renders the feature sets again when the files they were rendered from change. The files are polled, so it
+// works on any file system, and a burst of changes is rendered once the files stay unchanged for the quiet period.
+// A change of the inputs of the pipeline steps, or of the project file, renders every feature set, a change of a
+// template only the feature sets using it.
+type Watcher struct {
+	w        io.Writer
+	logger   *slog.Logger
+	args     Args
+	interval time.Duration
+	quiet    time.Duration
+	// The result of the pipeline steps, nil if they failed
+	context map[string]interface{}
+	// The stamps of the files read by the pipeline steps
+	inputs map[string]fileStamp
+	// The stamps of the template files read per feature set
+	templates map[string]map[string]fileStamp
+	// Parses the arguments again when the project file changes, nil to keep them
+	reload func() (Args, error)
+}
+
+func NewWatcher(w io.Writer, logger *slog.Logger, args Args) *Watcher {
+	return &Watcher{w: w, logger: logger, args: args, interval: 500 * time.Millisecond, quiet: 200 * time.Millisecond}
+}
+
+// Run renders every feature set, then renders them again on changes until stop is closed, a nil stop never is. The
+// errors are printed and the watcher keeps running, the next change renders again.
+func (watcher *Watcher) Run(stop <-chan struct{}) error {
+	watcher.renderAll()
+	for {
+		changed, ok := watcher.waitForChanges(stop)
+		if !ok {
+			return nil
+		}
+		fmt.Fprintf(watcher.w, "changed: %s\n", strings.Join(changed, ", "))
+		watcher.renderChanged(changed)
+	}
+}
+
+// RunUntilSignal runs the watcher until SIGINT or SIGTERM, the render in progress is finished first.
+func (watcher *Watcher) RunUntilSignal() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := watcher.Run(ctx.Done())
+	fmt.Fprintln(watcher.w, "stopped")
+	return err
+}
+
+// renderAll runs the pipeline steps and renders every feature set. The project file is read again first, it's watched
+// as an input of the steps.
+func (watcher *Watcher) renderAll() {
+	watcher.context = nil
+	watcher.templates = make(map[string]map[string]fileStamp)
+	// the files are stamped before they are read, a change made meanwhile is seen by the next poll
+	project, _ := findProjectFile(watcher.args.ConfigDir)
+	projectStamps := stampFiles([]string{project})
+	var err error
+	if watcher.reload != nil {
+		var args Args
+		if args, err = watcher.reload(); err == nil {
+			watcher.args = args
+		}
+	}
+	tracker := NewFileTracker()
+	var context map[string]interface{}
+	if err == nil {
+		context, err = runPipeline(watcher.args, tracker, watcher.logger)
+	}
+	var featureSets []string
+	if err == nil {
+		featureSets, err = selectFeatureSets(context)
+	}
+	watcher.inputs = tracker.Stamps()
+	if project != "" {
+		watcher.inputs[project] = projectStamps[project]
+	}
+	if err != nil {
+		watcher.report(err)
+		return
+	}
+	watcher.context = context
+	watcher.render(featureSets)
+	fmt.Fprintf(watcher.w, "watching %d files, press Ctrl-C to stop\n", len(watcher.files()))
+}
+
+func (watcher *Watcher) render(featureSets []string) {
+	for _, result := range renderFeatureSets(watcher.context, featureSets) {
+		watcher.templates[result.featureSet] = result.stamps
+		if _, err := writeFeatureSet(watcher.w, watcher.context, result); err != nil {
+			watcher.report(err)
+			continue
+		}
+		fmt.Fprintf(watcher.w, "rendered feature set %s\n", result.featureSet)
+	}
+}
+
+// renderChanged renders the feature sets affected by the changed files. A change of an input, or of a dir as a
+// template may be added or removed, renders everything.
+func (watcher *Watcher) renderChanged(changed []string) {
+	if watcher.context == nil || watcher.affects(watcher.inputs, changed) {
+		watcher.renderAll()
+		return
+	}
+	for _, stamps := range watcher.templates {
+		for _, file := range changed {
+			if stamps[file].isDir {
+				watcher.renderAll()
+				return
+			}
+		}
+	}
+	// the partials are parsed again
+	watcher.context["templates"] = NewTemplateCache(watcher.args, propertiesLookup(watcher.context))
+	featureSets := make([]string, 0)
+	for _, featureSet := range slices.Sorted(maps.Keys(watcher.templates)) {
+		if watcher.affects(watcher.templates[featureSet], changed) {
+			featureSets = append(featureSets, featureSet)
+		}
+	}
+	watcher.render(featureSets)
+}
+
+func (watcher *Watcher) affects(stamps map[string]fileStamp, changed []string) bool {
+	for _, file := range changed {
+		if _, ok := stamps[file]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForChanges polls the files until some change and then stay unchanged for the quiet period, false if stopped.
+func (watcher *Watcher) waitForChanges(stop <-chan struct{}) ([]string, bool) {
+	for {
+		select {
+		case <-stop:
+			return nil, false
+		case <-time.After(watcher.interval):
+		}
+		if len(watcher.changed()) == 0 {
+			continue
+		}
+		for {
+			burst := stampFiles(watcher.files())
+			select {
+			case <-stop:
+				return nil, false
+			case <-time.After(watcher.quiet):
+			}
+			if len(changedFiles(burst)) == 0 {
+				break
+			}
+		}
+		return watcher.changed(), true
+	}
+}
+
+// changed returns the watched files changed since they were read, in name order.
+func (watcher *Watcher) changed() []string {
+	changed := changedFiles(watcher.inputs)
+	for _, stamps := range watcher.templates {
+		for _, file := range changedFiles(stamps) {
+			if !slices.Contains(changed, file) {
+				changed = append(changed, file)
+			}
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// files returns the watched files in name order.
+func (watcher *Watcher) files() []string {
+	files := slices.Collect(maps.Keys(watcher.inputs))
+	for _, stamps := range watcher.templates {
+		for file := range stamps {
+			if !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+	slices.Sort(files)
+	return files
+}
+
+func (watcher *Watcher) report(err error) {
+	fmt.Fprintf(watcher.w, "error: %v\n", err)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a buffer written by the watcher and read by the test
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.String()
+}
+
+func TestWatcher_Run(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"configs/config.yaml":        "Foo:\n  feature-set: one\nBar:\n  feature-set: two\n",
+		"configs/features.txt":       "Foo\nBar\n",
+		"configs/mapping.properties": "",
+		"configs/examplar.yaml":      "feature-set: [one, two]\n",
+		"templates/one.tmpl":         "{{ range .features }}{{ .Name }}{{ end }}\n",
+		"templates/two.tmpl":         "{{ range .features }}{{ .Name }}{{ end }}\n",
+	}
+	write := func(file, content string) {
+		t.Helper()
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, content := range files {
+		write(file, content)
+	}
+	out := &syncBuffer{}
+	args := Args{
+		ConfigDir:          filepath.Join(dir, "configs"),
+		ConfigFile:         "config.yaml",
+		FeatureFile:        []string{"features.txt"},
+		FeatureMappingFile: "mapping.properties",
+		DuplicatePolicy:    "first",
+		TemplateDir:        filepath.Join(dir, "templates"),
+		OutputDir:          filepath.Join(dir, "out"),
+	}
+	watcher := NewWatcher(out, nil, args)
+	watcher.reload = func() (Args, error) {
+		reloaded := args
+		return reloaded, applyProjectFile(&reloaded, args.ConfigDir, "")
+	}
+	watcher.interval = 5 * time.Millisecond
+	watcher.quiet = 5 * time.Millisecond
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- watcher.Run(stop) }()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("output doesn't contain %q:\n%s", want, out.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor("watching")
+	// a template renders its feature set only
+	write("templates/one.tmpl", "one: {{ range .features }}{{ .Name }}{{ end }}\n")
+	changed := "changed: " + filepath.Join(dir, "templates/one.tmpl") + "\n"
+	waitFor(changed + "rendered feature set one\n")
+	if _, after, _ := strings.Cut(out.String(), changed); strings.Contains(after, "two") {
+		t.Errorf("feature set two rendered again:\n%s", after)
+	}
+	output, err := os.ReadFile(filepath.Join(dir, "out/one"))
+	if err != nil || string(output) != "one: Foo\n" {
+		t.Errorf("out/one = %q, %v", output, err)
+	}
+	// an error is shown and the watcher keeps running
+	write("configs/config.yaml", "Foo: [\n")
+	waitFor("error: step config:")
+	write("configs/config.yaml", files["configs/config.yaml"])
+	changed = "changed: " + filepath.Join(dir, "configs/config.yaml") + "\n"
+	waitFor(changed + "rendered feature set one\nrendered feature set two\nwatching")
+	// the project file is read again
+	write("configs/examplar.yaml", "feature-set: [two]\n")
+	changed = "changed: " + filepath.Join(dir, "configs/examplar.yaml") + "\n"
+	waitFor(changed + "rendered feature set two\nwatching")
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestWatcher_RunUntilSignal(t *testing.T) {
+	out := &syncBuffer{}
+	// the pipeline fails without inputs, the watcher keeps running
+	watcher := NewWatcher(out, nil, Args{ConfigDir: t.TempDir(), ConfigFile: "config.yaml"})
+	watcher.interval = 5 * time.Millisecond
+	done := make(chan error)
+	go func() { done <- watcher.RunUntilSignal() }()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "error: ") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no render:\n%s", out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil || !strings.HasSuffix(out.String(), "stopped\n") {
+			t.Errorf("RunUntilSignal() = %v, output:\n%s", err, out.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunUntilSignal() not stopped by SIGINT")
+	}
+}